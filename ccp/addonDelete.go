@@ -0,0 +1,92 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// AddonDeleteResult reports which addons were deleted and which are still installed
+type AddonDeleteResult struct {
+	Deleted  []string `json:"deleted"`
+	Leftover []string `json:"leftover,omitempty"`
+}
+
+// DeleteAddonFromRegistry deletes an addon and every installed addon that needs it, dependents first, e.g.
+// ccp-istio-cr before ccp-istio-operator. It waits until each addon is gone from GetClusterInstalledAddons before
+// deleting the next. If one does not go, it and the addons not yet deleted are returned as leftovers with the error
+func (s *Client) DeleteAddonFromRegistry(ctx context.Context, clusterUUID string, registry *AddonRegistry, addonName string) (*AddonDeleteResult, error) {
+	Debug(1, "Entered DeleteAddonFromRegistry for UUID "+clusterUUID+" addon "+addonName)
+
+	if clusterUUID == "" {
+		return nil, errors.New("Cluster UUID is required")
+	}
+
+	installedAddons, err := s.GetClusterInstalledAddons(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+	var installed []string
+	for _, addon := range installedAddons.Results {
+		installed = append(installed, addon.Name)
+	}
+
+	order, err := registry.DeleteOrder([]string{addonName}, installed)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AddonDeleteResult{}
+	for i, name := range order {
+		err = s.deleteAddonAndWait(ctx, clusterUUID, name)
+		if err != nil {
+			result.Leftover = order[i:]
+			return result, errors.New("Addons still installed: " + strings.Join(result.Leftover, ", ") + ": " + err.Error())
+		}
+		result.Deleted = append(result.Deleted, name)
+	}
+
+	return result, nil
+}
+
+// deleteAddonAndWait deletes one addon, if it is listed, and waits until it is gone
+func (s *Client) deleteAddonAndWait(ctx context.Context, clusterUUID string, addonName string) error {
+
+	_, err := s.GetClusterAddon(clusterUUID, addonName)
+	if errors.Is(err, ErrAddonNotInstalled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/" + addonName + "/"
+	Debug(2, "Sending HTTP delete to "+url)
+
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.doRequest(req)
+	if err != nil {
+		return err
+	}
+
+	return s.waitForAddonDeleted(ctx, clusterUUID, addonName)
+}
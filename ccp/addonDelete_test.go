@@ -0,0 +1,83 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeleteAddon(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	tests := []struct {
+		name      string
+		addon     string
+		installed []string
+		deletes   []string
+	}{
+		{"istio", "istio", []string{"ccp-istio-operator", "ccp-istio-cr", "ccp-monitor"}, []string{"ccp-istio-cr", "ccp-istio-operator"}},
+		{"istio instance", "ccp-istio-cr", []string{"ccp-istio-operator", "ccp-istio-cr"}, []string{"ccp-istio-cr"}},
+		{"monitoring", "monitoring", []string{"ccp-monitor", "ccp-efk"}, []string{"ccp-monitor"}},
+		{"not installed", "monitoring", []string{"ccp-efk"}, nil},
+		{"custom chart", "my-chart", []string{"my-chart"}, []string{"my-chart"}},
+	}
+
+	for _, test := range tests {
+		server := &addonServer{catalog: testCatalog()}
+		for _, name := range test.installed {
+			server.install(name, "/opt/ccp/charts/"+name+".tgz", "INSTALLED")
+		}
+		client := newTestClient(t, server)
+
+		err := client.DeleteAddon("cluster-1", test.addon)
+		if err != nil {
+			t.Errorf("%s: error %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(server.deletes, test.deletes) {
+			t.Errorf("%s: deleted %v, want %v", test.name, server.deletes, test.deletes)
+		}
+	}
+}
+
+func TestDeleteAddonFromRegistryLeftover(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	server := &addonServer{catalog: testCatalog()}
+	server.install("ccp-istio-operator", "/opt/ccp/charts/ccp-istio-operator.tgz", "INSTALLED")
+	server.install("ccp-istio-cr", "/opt/ccp/charts/ccp-istio-cr.tgz", "INSTALLED")
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" && strings.Contains(r.URL.Path, "ccp-istio-operator") {
+			http.Error(w, "addon is busy", http.StatusConflict)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+
+	result, err := client.DeleteAddonFromRegistry(context.Background(), "cluster-1", testRegistry(t), "istio")
+	if err == nil || !strings.Contains(err.Error(), "addon is busy") {
+		t.Errorf("DeleteAddonFromRegistry error %v, want the API error", err)
+	}
+	if result == nil || !reflect.DeepEqual(result.Deleted, []string{"ccp-istio-cr"}) || !reflect.DeepEqual(result.Leftover, []string{"ccp-istio-operator"}) {
+		t.Errorf("DeleteAddonFromRegistry = %+v, want ccp-istio-cr deleted and ccp-istio-operator left", result)
+	}
+}
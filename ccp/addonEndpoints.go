@@ -0,0 +1,82 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"errors"
+	"sort"
+)
+
+// AddonEndpoint is where the service of an installed addon, such as Grafana or Kibana, can be reached.
+// ServiceURL is empty for addons which have no service
+type AddonEndpoint struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+	Namespace   string `json:"namespace"`
+	Status      string `json:"status"`
+	ServiceURL  string `json:"serviceUrl,omitempty"`
+}
+
+// GetAddonEndpoints returns the service URL and namespace of every installed addon, sorted by name
+func (s *Client) GetAddonEndpoints(clusterUUID string) ([]AddonEndpoint, error) {
+	Debug(1, "Entered GetAddonEndpoints for UUID "+clusterUUID)
+
+	if clusterUUID == "" {
+		return nil, errors.New("Cluster UUID is required")
+	}
+
+	var endpoints []AddonEndpoint
+
+	pages := s.addonPages(clusterUUID)
+	for {
+		var page struct {
+			Results []Results `json:"results"`
+		}
+		if !pages.next(&page) {
+			break
+		}
+		for _, addon := range page.Results {
+			endpoint := AddonEndpoint{}
+			if addon.Name != nil {
+				endpoint.Name = *addon.Name
+			}
+			if addon.DisplayName != nil {
+				endpoint.DisplayName = *addon.DisplayName
+			}
+			if addon.Namespace != nil {
+				endpoint.Namespace = *addon.Namespace
+			}
+			if addon.AddonStatus != nil {
+				if addon.AddonStatus.Status != nil {
+					endpoint.Status = *addon.AddonStatus.Status
+				}
+				if addon.AddonStatus.ServiceURL != nil {
+					endpoint.ServiceURL = *addon.AddonStatus.ServiceURL
+				}
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if pages.err != nil {
+		return nil, pages.err
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Name < endpoints[j].Name
+	})
+
+	return endpoints, nil
+}
@@ -0,0 +1,54 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetAddonEndpoints(t *testing.T) {
+
+	server := &addonServer{pageSize: 2, relative: true}
+	monitor := server.install("ccp-monitor", "/opt/ccp/charts/ccp-monitor.tgz", "INSTALLED")
+	monitor.DisplayName = String("Monitoring")
+	monitor.Namespace = String("ccp")
+	monitor.AddonStatus.ServiceURL = String("https://10.0.0.10/grafana")
+	efk := server.install("ccp-efk", "/opt/ccp/charts/ccp-efk.tgz", "INSTALLING")
+	efk.Namespace = String("ccp")
+	server.install("kubernetes-dashboard", "/opt/ccp/charts/kubernetes-dashboard.tgz", "INSTALLED").AddonStatus.ServiceURL = String("/dashboard")
+	server.installed = append(server.installed, Results{Name: String("ccp-hxcsi")})
+	client := newTestClient(t, server)
+
+	endpoints, err := client.GetAddonEndpoints("cluster-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AddonEndpoint{
+		{Name: "ccp-efk", Namespace: "ccp", Status: "INSTALLING"},
+		{Name: "ccp-hxcsi"},
+		{Name: "ccp-monitor", DisplayName: "Monitoring", Namespace: "ccp", Status: "INSTALLED", ServiceURL: "https://10.0.0.10/grafana"},
+		{Name: "kubernetes-dashboard", Status: "INSTALLED", ServiceURL: "/dashboard"},
+	}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("GetAddonEndpoints = %+v, want %+v", endpoints, want)
+	}
+
+	if _, err := client.GetAddonEndpoints(""); err == nil {
+		t.Error("GetAddonEndpoints without a cluster UUID did not fail")
+	}
+}
@@ -0,0 +1,138 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Results of an addon in InstallAddons
+const (
+	AddonResultInstalled        = "installed"
+	AddonResultAlreadyInstalled = "already installed"
+	AddonResultFailed           = "failed"
+	AddonResultSkipped          = "skipped"
+)
+
+// AddonInstallResult is what happened to one addon in InstallAddons. Error is set for failed and skipped addons
+type AddonInstallResult struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	Err    error  `json:"-"`
+}
+
+// InstallAddons installs a set of addons from the cluster catalogue, see InstallAddonsFromRegistry
+func (s *Client) InstallAddons(ctx context.Context, clusterUUID string, addonNames []string) ([]AddonInstallResult, error) {
+
+	if clusterUUID == "" {
+		return nil, errors.New("Cluster UUID is required")
+	}
+
+	registry, err := s.GetAddonRegistry(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.InstallAddonsFromRegistry(ctx, clusterUUID, registry, addonNames)
+}
+
+// InstallAddonsFromRegistry installs a set of addons with the addons they need and their dependencies. The
+// whole set is checked for unknown and conflicting addons before anything is sent. Addons which do not need each
+// other are installed at the same time, an addon which needs another waits for it and is skipped if it failed.
+// Addons which are already installed are left as they are. Each addon is waited for up to AddonInstallTimeout,
+// or until ctx is done. There is a result for every addon, in install order, and an error naming the addons
+// which did not install
+func (s *Client) InstallAddonsFromRegistry(ctx context.Context, clusterUUID string, registry *AddonRegistry, addonNames []string) ([]AddonInstallResult, error) {
+	Debug(1, "Entered InstallAddonsFromRegistry for UUID "+clusterUUID+" addons "+strings.Join(addonNames, ","))
+
+	installedAddons, err := s.GetClusterInstalledAddons(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+	var installed []string
+	isInstalled := map[string]bool{}
+	for _, addon := range installedAddons.Results {
+		installed = append(installed, addon.Name)
+		isInstalled[addon.Name] = addon.AddonStatus.Status == "INSTALLED"
+	}
+
+	order, err := registry.InstallOrder(addonNames, installed)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]AddonInstallResult, len(order))
+	index := map[string]int{}
+	done := map[string]chan struct{}{}
+	for i, entry := range order {
+		index[entry.Name] = i
+		done[entry.Name] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for i, entry := range order {
+		wg.Add(1)
+		go func(result *AddonInstallResult, entry CatalogEntry) {
+			defer wg.Done()
+			defer close(done[entry.Name])
+			result.Name = entry.Name
+
+			for _, required := range registry.requires[entry.Name] {
+				if _, ok := done[required]; !ok {
+					continue
+				}
+				<-done[required]
+				if results[index[required]].Err != nil {
+					result.Result = AddonResultSkipped
+					result.Err = errors.New("needs " + required + " which did not install")
+					result.Error = result.Err.Error()
+					return
+				}
+			}
+
+			if isInstalled[entry.Name] {
+				result.Result = AddonResultAlreadyInstalled
+				return
+			}
+
+			err := s.installAddonEntry(ctx, clusterUUID, entry)
+			if err != nil {
+				result.Result = AddonResultFailed
+				result.Err = err
+				result.Error = err.Error()
+				return
+			}
+			result.Result = AddonResultInstalled
+		}(&results[i], entry)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return results, errors.New("Addons did not install: " + strings.Join(failed, ", "))
+	}
+
+	return results, nil
+}
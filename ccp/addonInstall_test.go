@@ -0,0 +1,109 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInstallAddons(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	server := &addonServer{catalog: testCatalog(), status: map[string]string{"ccp-istio-operator": "FAILED"}}
+	server.install("ccp-efk", "/opt/ccp/charts/ccp-efk.tgz", "INSTALLED")
+	client := newTestClient(t, server)
+
+	results, err := client.InstallAddons(context.Background(), "cluster-1", []string{"istio", "logging", "monitoring"})
+	if err == nil || !strings.Contains(err.Error(), "ccp-istio-operator, ccp-istio-cr") {
+		t.Errorf("InstallAddons error %v, want istio not installed", err)
+	}
+
+	want := map[string]string{
+		"ccp-efk":            AddonResultAlreadyInstalled,
+		"ccp-istio-operator": AddonResultFailed,
+		"ccp-istio-cr":       AddonResultSkipped,
+		"ccp-monitor":        AddonResultInstalled,
+	}
+	got := map[string]string{}
+	for _, result := range results {
+		got[result.Name] = result.Result
+		if (result.Err != nil) != (result.Error != "") {
+			t.Errorf("%s: Err %v and Error %q do not match", result.Name, result.Err, result.Error)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InstallAddons results %v, want %v", got, want)
+	}
+	for _, name := range server.posts {
+		if name == "ccp-istio-cr" || name == "ccp-efk" {
+			t.Errorf("%s was posted", name)
+		}
+	}
+
+	// conflicts are refused before anything is posted
+	posts := len(server.posts)
+	_, err = client.InstallAddons(context.Background(), "cluster-1", []string{"istio", "harbor"})
+	if err == nil || len(server.posts) != posts {
+		t.Errorf("InstallAddons of conflicting addons error %v, posted %v", err, server.posts[posts:])
+	}
+}
+
+func TestInstallAddonsConcurrently(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	// each independent addon is only accepted once the other has been posted too
+	var mu sync.Mutex
+	arrived := map[string]chan struct{}{"ccp-monitor": make(chan struct{}), "ccp-efk": make(chan struct{})}
+	other := map[string]string{"ccp-monitor": "ccp-efk", "ccp-efk": "ccp-monitor"}
+	server := &addonServer{catalog: testCatalog()}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			body, _ := ioutil.ReadAll(r.Body)
+			var entry CatalogEntry
+			json.Unmarshal(body, &entry)
+			mu.Lock()
+			close(arrived[entry.Name])
+			mu.Unlock()
+			select {
+			case <-arrived[other[entry.Name]]:
+			case <-time.After(2 * time.Second):
+				http.Error(w, entry.Name+" was posted on its own", http.StatusConflict)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		server.ServeHTTP(w, r)
+	}))
+
+	results, err := client.InstallAddons(context.Background(), "cluster-1", []string{"monitoring", "logging"})
+	if err != nil {
+		t.Fatalf("InstallAddons = %+v, %v", results, err)
+	}
+	if len(server.posts) != 2 {
+		t.Errorf("posted %v, want ccp-efk and ccp-monitor", server.posts)
+	}
+}
@@ -0,0 +1,102 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseAddonValues reads a Helm values document, YAML or JSON, into a map
+func ParseAddonValues(data []byte) (map[string]interface{}, error) {
+
+	var values map[string]interface{}
+	err := yaml.Unmarshal(data, &values)
+	if err != nil {
+		return nil, errors.New("Cannot read addon values: " + err.Error())
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+
+	return values, nil
+}
+
+// MergeAddonValues merges Helm values over a catalogue entry's own overrides and returns them as the YAML
+// string sent in the addon overrides. Nested maps are merged, anything else in values replaces the overrides
+func MergeAddonValues(entry *CatalogEntry, values map[string]interface{}) (string, error) {
+
+	if len(values) == 0 {
+		return entry.Overrides, nil
+	}
+
+	base, err := ParseAddonValues([]byte(entry.Overrides))
+	if err != nil {
+		return "", errors.New("Catalogue overrides of addon " + entry.Name + ": " + err.Error())
+	}
+
+	merged, err := yaml.Marshal(mergeValues(base, values))
+	if err != nil {
+		return "", err
+	}
+
+	return string(merged), nil
+}
+
+func mergeValues(base, values map[string]interface{}) map[string]interface{} {
+
+	for key, value := range values {
+		valueMap, isMap := value.(map[string]interface{})
+		baseMap, baseIsMap := base[key].(map[string]interface{})
+		if isMap && baseIsMap {
+			base[key] = mergeValues(baseMap, valueMap)
+		} else {
+			base[key] = value
+		}
+	}
+
+	return base
+}
+
+// InstallAddonWithOverrides installs an addon like InstallAddon with Helm values, a YAML or JSON document, merged
+// over the catalogue overrides. See InstallAddonFromRegistry
+func (s *Client) InstallAddonWithOverrides(ctx context.Context, clusterUUID string, addonName string, values []byte) error {
+
+	if clusterUUID == "" {
+		return errors.New("Cluster UUID is required")
+	}
+
+	registry, err := s.GetAddonRegistry(clusterUUID)
+	if err != nil {
+		return err
+	}
+
+	return s.InstallAddonFromRegistry(ctx, clusterUUID, registry, addonName, values)
+}
+
+// InstallAddonWithOverridesFile is InstallAddonWithOverrides with the values read from a YAML or JSON file
+func (s *Client) InstallAddonWithOverridesFile(ctx context.Context, clusterUUID string, addonName string, path string) error {
+
+	values, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return s.InstallAddonWithOverrides(ctx, clusterUUID, addonName, values)
+}
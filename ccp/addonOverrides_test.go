@@ -0,0 +1,112 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeAddonValues(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		overrides string
+		values    string
+		want      map[string]interface{}
+	}{
+		{"no values", "retention: 7d\n", "", map[string]interface{}{"retention": "7d"}},
+		{"no overrides", "", "retention: 30d", map[string]interface{}{"retention": "30d"}},
+		{"replace", "retention: 7d\nreplicas: 1\n", `{"retention": "30d"}`, map[string]interface{}{"retention": "30d", "replicas": 1}},
+		{"nested", "storage:\n  size: 10Gi\n  class: standard\n", "storage:\n  size: 50Gi\n",
+			map[string]interface{}{"storage": map[string]interface{}{"size": "50Gi", "class": "standard"}}},
+		{"map over value", "storage: none\n", "storage:\n  size: 50Gi\n",
+			map[string]interface{}{"storage": map[string]interface{}{"size": "50Gi"}}},
+		{"list replaced", "hosts: [a, b]\n", "hosts: [c]\n", map[string]interface{}{"hosts": []interface{}{"c"}}},
+	}
+
+	for _, test := range tests {
+		values, err := ParseAddonValues([]byte(test.values))
+		if err != nil {
+			t.Errorf("%s: ParseAddonValues error %v", test.name, err)
+			continue
+		}
+		merged, err := MergeAddonValues(&CatalogEntry{Name: "ccp-efk", Overrides: test.overrides}, values)
+		if err != nil {
+			t.Errorf("%s: MergeAddonValues error %v", test.name, err)
+			continue
+		}
+		var got map[string]interface{}
+		err = yaml.Unmarshal([]byte(merged), &got)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: MergeAddonValues = %q, want %v", test.name, merged, test.want)
+		}
+	}
+}
+
+func TestParseAddonValuesErrors(t *testing.T) {
+
+	for _, data := range []string{"- a\n- b\n", "retention: [7d\n"} {
+		if _, err := ParseAddonValues([]byte(data)); err == nil {
+			t.Errorf("ParseAddonValues(%q) did not fail", data)
+		}
+	}
+
+	_, err := MergeAddonValues(&CatalogEntry{Name: "ccp-efk", Overrides: "- a\n"}, map[string]interface{}{"a": 1})
+	if err == nil || !strings.Contains(err.Error(), "ccp-efk") {
+		t.Errorf("MergeAddonValues with bad catalogue overrides error %v", err)
+	}
+}
+
+func TestInstallAddonWithOverrides(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	catalog := testCatalog()
+	efk := catalog["_ccp-efk"]
+	efk.Overrides = "retention: 7d\nreplicas: 1\n"
+	catalog["_ccp-efk"] = efk
+	server := &addonServer{catalog: catalog}
+	client := newTestClient(t, server)
+
+	err := client.InstallAddonWithOverrides(context.Background(), "cluster-1", "logging", []byte("retention: 30d\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.installed) != 1 || server.installed[0].Overrides == nil {
+		t.Fatalf("installed %v, want ccp-efk with overrides", server.installed)
+	}
+	var overrides map[string]interface{}
+	yaml.Unmarshal([]byte(*server.installed[0].Overrides), &overrides)
+	if want := map[string]interface{}{"retention": "30d", "replicas": 1}; !reflect.DeepEqual(overrides, want) {
+		t.Errorf("posted overrides %v, want %v", overrides, want)
+	}
+
+	// the values of an installed addon are not changed
+	err = client.InstallAddonWithOverrides(context.Background(), "cluster-1", "logging", []byte("retention: 90d\n"))
+	if err == nil || !strings.Contains(err.Error(), "already installed") {
+		t.Errorf("InstallAddonWithOverrides of an installed addon error %v", err)
+	}
+	// bad values are refused before anything is sent
+	err = client.InstallAddonWithOverrides(context.Background(), "cluster-1", "monitoring", []byte("- a\n"))
+	if err == nil || len(server.posts) != 1 {
+		t.Errorf("InstallAddonWithOverrides with bad values error %v, posted %v", err, server.posts)
+	}
+}
@@ -0,0 +1,179 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// InstalledAddonIterator walks the installed addons of a cluster, reading the next page when one is used up
+//
+//	addons := client.InstalledAddons(clusterUUID)
+//	for addons.Next() {
+//		fmt.Println(addons.Addon().Name)
+//	}
+//	if addons.Err() != nil {
+//		...
+//	}
+type InstalledAddonIterator struct {
+	pages addonPageReader
+	page  *ClusterInstalledAddons
+	index int
+	count int64
+}
+
+// InstalledAddons returns an iterator over every installed addon of a cluster. Nothing is read until Next
+func (s *Client) InstalledAddons(clusterUUID string) *InstalledAddonIterator {
+	return &InstalledAddonIterator{pages: s.addonPages(clusterUUID)}
+}
+
+// Next moves to the next addon. It returns false when there are no more or a page could not be read, see Err
+func (it *InstalledAddonIterator) Next() bool {
+
+	for {
+		if it.page != nil && it.index+1 < len(it.page.Results) {
+			it.index++
+			return true
+		}
+
+		var page ClusterInstalledAddons
+		if !it.pages.next(&page) {
+			return false
+		}
+		it.page = &page
+		it.index = -1
+		it.count = page.Count
+	}
+}
+
+// Addon returns the current addon
+func (it *InstalledAddonIterator) Addon() *InstalledAddon {
+	if it.page == nil || it.index < 0 {
+		return nil
+	}
+	return &it.page.Results[it.index]
+}
+
+// Count returns the number of installed addons reported by CCP, once the first page is read
+func (it *InstalledAddonIterator) Count() int64 {
+	return it.count
+}
+
+// Err returns the error that stopped Next, if any. It is nil while Next returns true
+func (it *InstalledAddonIterator) Err() error {
+	return it.pages.err
+}
+
+// addonPageReader reads the pages of /v3/clusters/<clusteruuid>/addons/ by following next
+type addonPageReader struct {
+	s       *Client
+	path    string // request path of the next page, "" after the last
+	seen    map[string]bool
+	err     error // set once next returns false
+	linkErr error // a bad link to the next page, reported when that page is wanted
+}
+
+func (s *Client) addonPages(clusterUUID string) addonPageReader {
+	return addonPageReader{
+		s:    s,
+		path: "/v3/clusters/" + clusterUUID + "/addons/",
+		seen: map[string]bool{},
+	}
+}
+
+// next reads the next page into page. It returns false after the last page or on an error
+func (p *addonPageReader) next(page interface{}) bool {
+
+	if p.linkErr != nil {
+		p.err, p.linkErr = p.linkErr, nil
+	}
+	if p.err != nil || p.path == "" {
+		return false
+	}
+	if p.seen[p.path] {
+		p.err = errors.New("Addon list page " + p.path + " was returned twice")
+		return false
+	}
+	p.seen[p.path] = true
+
+	pageURL := p.s.BaseURL + p.path
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	bytes, err := p.s.doRequest(req)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	Debug(3, string(bytes))
+
+	var links struct {
+		Next *string `json:"next"`
+	}
+	err = json.Unmarshal(bytes, &links)
+	if err == nil {
+		err = json.Unmarshal(bytes, page)
+	}
+	if err != nil {
+		p.err = err
+		return false
+	}
+
+	// the page is good even if the link to the next one is not, the error stops the page after it
+	p.path, p.linkErr = nextPagePath(p.s.BaseURL, pageURL, links.Next)
+	return true
+}
+
+// nextPagePath returns the request path, relative to baseURL, of the page after pageURL. A relative next link,
+// such as ?page=2, is resolved against pageURL. Only the path and query are used so the request goes to the
+// client's BaseURL, with its token, whatever host CCP puts in the link. A path prefix in baseURL, e.g. a CCP
+// behind a proxy at https://proxy/ccp, is taken off the link so it is not sent twice
+func nextPagePath(baseURL string, pageURL string, next *string) (string, error) {
+
+	if next == nil || *next == "" {
+		return "", nil
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	link, err := url.Parse(*next)
+	if err != nil {
+		return "", errors.New("Cannot read addon list next page " + *next + ": " + err.Error())
+	}
+
+	resolved := page.ResolveReference(link)
+	path := resolved.EscapedPath()
+	if prefix := strings.TrimSuffix(base.EscapedPath(), "/"); prefix != "" && strings.HasPrefix(path, prefix+"/") {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	if resolved.RawQuery != "" {
+		path += "?" + resolved.RawQuery
+	}
+
+	return path, nil
+}
@@ -0,0 +1,156 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNextPagePath(t *testing.T) {
+
+	const page = "/v3/clusters/c1/addons/"
+
+	tests := []struct {
+		baseURL string
+		next    *string
+		want    string
+		err     bool
+	}{
+		{"https://ccp.local", nil, "", false},
+		{"https://ccp.local", String(""), "", false},
+		{"https://ccp.local", String("http://10.0.0.1/v3/clusters/c1/addons/?page=2"), page + "?page=2", false},
+		{"https://ccp.local", String("?page=2"), page + "?page=2", false},
+		{"https://ccp.local", String("/v3/clusters/c1/addons/?page=2&size=10"), page + "?page=2&size=10", false},
+		{"https://ccp.local", String("more/"), page + "more/", false},
+		{"https://proxy.local/ccp", String("?page=2"), page + "?page=2", false},
+		{"https://proxy.local/ccp/", String("?page=2"), page + "?page=2", false},
+		{"https://proxy.local/ccp", String("https://proxy.local/ccp/v3/clusters/c1/addons/?page=3"), page + "?page=3", false},
+		{"https://proxy.local/ccp", String("/ccp/v3/clusters/c1/addons/?page=3"), page + "?page=3", false},
+		{"https://proxy.local/ccp", String("http://10.0.0.1/v3/clusters/c1/addons/?page=3"), page + "?page=3", false},
+		{"https://ccp.local", String("http://[::1"), "", true},
+	}
+
+	for _, test := range tests {
+		got, err := nextPagePath(test.baseURL, strings.TrimSuffix(test.baseURL, "/")+page, test.next)
+		if (err != nil) != test.err || got != test.want {
+			next := "<nil>"
+			if test.next != nil {
+				next = *test.next
+			}
+			t.Errorf("nextPagePath(%s, %s) = %q, %v, want %q", test.baseURL, next, got, err, test.want)
+		}
+	}
+}
+
+func TestInstalledAddons(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		pageSize int
+		relative bool
+		prefix   string
+	}{
+		{"one page", 0, false, ""},
+		{"absolute links", 2, false, ""},
+		{"relative links", 2, true, ""},
+		{"relative links with a base path", 2, true, "/ccp"},
+		{"one per page", 1, true, "/ccp"},
+	}
+
+	var want []string
+	for i := 1; i <= 5; i++ {
+		want = append(want, "addon-"+strconv.Itoa(i))
+	}
+
+	for _, test := range tests {
+		server := &addonServer{pageSize: test.pageSize, relative: test.relative}
+		for _, name := range want {
+			server.install(name, "/opt/ccp/charts/"+name+".tgz", "INSTALLED")
+		}
+		var handler http.Handler = server
+		if test.prefix != "" {
+			handler = http.StripPrefix(test.prefix, server)
+		}
+		client := newTestClient(t, handler)
+		client.BaseURL += test.prefix
+
+		var names []string
+		addons := client.InstalledAddons("cluster-1")
+		for addons.Next() {
+			names = append(names, addons.Addon().Name)
+		}
+		if addons.Err() != nil {
+			t.Errorf("%s: error %v", test.name, addons.Err())
+		}
+		if !reflect.DeepEqual(names, want) || addons.Count() != int64(len(want)) {
+			t.Errorf("%s: addons %v count %d, want %v", test.name, names, addons.Count(), want)
+		}
+
+		all, err := client.GetClusterInstalledAddons("cluster-1")
+		if err != nil || len(all.Results) != len(want) {
+			t.Errorf("%s: GetClusterInstalledAddons = %v, %v", test.name, all, err)
+		}
+	}
+}
+
+func TestInstalledAddonsErr(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		pages map[string]string
+		names []string
+		err   string
+	}{
+		{"bad next link", map[string]string{
+			"": `{"count": 3, "next": "http://[::1", "results": [{"name": "a"}, {"name": "b"}]}`,
+		}, []string{"a", "b"}, "Cannot read addon list next page"},
+		{"page repeated", map[string]string{
+			"":  `{"count": 3, "next": "?page=2", "results": [{"name": "a"}]}`,
+			"2": `{"count": 3, "next": "?page=2", "results": [{"name": "b"}]}`,
+		}, []string{"a", "b"}, "returned twice"},
+		{"bad page", map[string]string{
+			"":  `{"count": 3, "next": "?page=2", "results": [{"name": "a"}]}`,
+			"2": `{"count": 3, "results": [`,
+		}, []string{"a"}, "unexpected end of JSON input"},
+	}
+
+	for _, test := range tests {
+		pages := test.pages
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(pages[r.URL.Query().Get("page")]))
+		}))
+
+		var names []string
+		addons := client.InstalledAddons("cluster-1")
+		for addons.Next() {
+			// the error is only reported once Next is false
+			if addons.Err() != nil {
+				t.Errorf("%s: Err() = %v while Next() is true", test.name, addons.Err())
+			}
+			names = append(names, addons.Addon().Name)
+		}
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%s: addons %v, want %v", test.name, names, test.names)
+		}
+		if addons.Err() == nil || !strings.Contains(addons.Err().Error(), test.err) {
+			t.Errorf("%s: Err() = %v, want %q", test.name, addons.Err(), test.err)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultAddonNamespace is used for catalogue entries which have no namespace
+const DefaultAddonNamespace = "ccp"
+
+// addonAliases are the short addon names accepted as well as the catalogue names
+var addonAliases = map[string]string{
+	"dashboard":  "kubernetes-dashboard",
+	"logging":    "ccp-efk",
+	"efk":        "ccp-efk",
+	"monitor":    "ccp-monitor",
+	"monitoring": "ccp-monitor",
+	"istio":      "ccp-istio-operator",
+	"harbor":     "ccp-harbor-operator",
+	"kubeflow":   "ccp-kubeflow",
+	"hxcsi":      "ccp-hxcsi",
+	"hx-csi":     "ccp-hxcsi",
+}
+
+// AddonRegistry holds the addons a cluster can install, with the dependencies and conflicts between them
+type AddonRegistry struct {
+	entries  map[string]CatalogEntry // by addon name, dependencies included
+	keys     map[string]string       // catalogue key without the leading _ to addon name
+	requires map[string][]string     // addon name to the addons that must be installed first
+}
+
+// NewAddonRegistry builds a registry from a catalogue, as returned by /v3/clusters/<clusteruuid>/catalog
+func NewAddonRegistry(catalog AddonsCatalogue) (*AddonRegistry, error) {
+
+	r := &AddonRegistry{
+		entries:  map[string]CatalogEntry{},
+		keys:     map[string]string{},
+		requires: map[string][]string{},
+	}
+
+	err := r.add(catalog, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *AddonRegistry) add(catalog AddonsCatalogue, parent string) error {
+
+	for key, entry := range catalog {
+		if entry.Name == "" {
+			return errors.New("Addon " + key + " in catalogue has no name")
+		}
+		if entry.Namespace == "" {
+			entry.Namespace = DefaultAddonNamespace
+		}
+		if existing, ok := r.entries[entry.Name]; ok && existing.URL != entry.URL {
+			return errors.New("Addon " + entry.Name + " is in the catalogue twice with different charts")
+		}
+
+		r.entries[entry.Name] = entry
+		r.keys[strings.TrimPrefix(key, "_")] = entry.Name
+		if parent != "" {
+			r.requires[entry.Name] = appendMissing(r.requires[entry.Name], parent)
+		}
+
+		err := r.add(entry.Dependencies, entry.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAddonRegistry builds the addon registry of a cluster from its addon catalogue
+func (s *Client) GetAddonRegistry(clusterUUID string) (*AddonRegistry, error) {
+	Debug(1, "Entered GetAddonRegistry for UUID "+clusterUUID)
+
+	if clusterUUID == "" {
+		return nil, errors.New("Cluster UUID is required")
+	}
+
+	catalog, err := s.GetAddonsCatalogue(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAddonRegistry(catalog)
+}
+
+// LoadAddonRegistry builds an addon registry from a local definition file. The file is JSON or YAML in the
+// same layout as the CCP catalogue, so a saved catalogue can be used as it is
+func LoadAddonRegistry(path string) (*AddonRegistry, error) {
+	Debug(1, "Entered LoadAddonRegistry for "+path)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, decode it generically then read it with the JSON field names
+	var document interface{}
+	err = yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, errors.New("Cannot read addon definitions " + path + ": " + err.Error())
+	}
+	jsonBody, err := json.Marshal(document)
+	if err != nil {
+		return nil, errors.New("Cannot read addon definitions " + path + ": " + err.Error())
+	}
+
+	var catalog AddonsCatalogue
+	err = json.Unmarshal(jsonBody, &catalog)
+	if err != nil {
+		return nil, errors.New("Cannot read addon definitions " + path + ": " + err.Error())
+	}
+
+	return NewAddonRegistry(catalog)
+}
+
+// Names returns the names of every addon in the registry, sorted
+func (r *AddonRegistry) Names() []string {
+
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Resolve returns the addon name for a name, catalogue key or short name such as monitoring
+func (r *AddonRegistry) Resolve(name string) (string, bool) {
+
+	if _, ok := r.entries[name]; ok {
+		return name, true
+	}
+	if resolved, ok := r.keys[strings.TrimPrefix(name, "_")]; ok {
+		return resolved, true
+	}
+	if resolved, ok := addonAliases[name]; ok {
+		if _, ok := r.entries[resolved]; ok {
+			return resolved, true
+		}
+	}
+
+	return "", false
+}
+
+// Get returns the catalogue entry of an addon
+func (r *AddonRegistry) Get(name string) (*CatalogEntry, bool) {
+
+	resolved, ok := r.Resolve(name)
+	if !ok {
+		return nil, false
+	}
+	entry := r.entries[resolved]
+
+	return &entry, true
+}
+
+// InstallOrder returns the addons to install for the names asked for, in the order to install them. Each addon
+// comes after the addons it needs and is followed by its own dependencies, so istio gives ccp-istio-operator then
+// ccp-istio-cr. An error is returned for unknown addons, dependency cycles and for any two addons that conflict,
+// counting the installed ones, so nothing is sent to CCP for a combination that cannot work
+func (r *AddonRegistry) InstallOrder(names []string, installed []string) ([]CatalogEntry, error) {
+
+	// everything asked for, what it needs and its dependencies
+	wanted := map[string]bool{}
+	var queue []string
+	for _, name := range names {
+		resolved, ok := r.Resolve(name)
+		if !ok {
+			return nil, errors.New("Unknown addon '" + name + "'. Options are: " + strings.Join(r.Names(), ", "))
+		}
+		queue = append(queue, resolved)
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if wanted[name] {
+			continue
+		}
+		wanted[name] = true
+		queue = append(queue, r.requires[name]...)
+		for _, dependency := range r.entries[name].Dependencies {
+			queue = append(queue, dependency.Name)
+		}
+	}
+
+	err := r.checkConflicts(wanted, installed)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := r.sortByRequires(wanted)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CatalogEntry
+	for _, name := range order {
+		entries = append(entries, r.entries[name])
+	}
+
+	return entries, nil
+}
+
+// DeleteOrder returns the installed addons to delete for the names asked for, in the order to delete them:
+// every installed addon that needs one of them goes first, so istio gives ccp-istio-cr then
+// ccp-istio-operator. Installed addons which are not in the registry can be named and are deleted on their own
+func (r *AddonRegistry) DeleteOrder(names []string, installed []string) ([]string, error) {
+
+	isInstalled := map[string]bool{}
+	for _, name := range installed {
+		isInstalled[name] = true
+	}
+
+	// everything asked for and the installed addons that need it
+	remove := map[string]bool{}
+	var queue []string
+	for _, name := range names {
+		resolved, ok := r.Resolve(name)
+		if !ok {
+			if !isInstalled[name] {
+				return nil, errors.New("Unknown addon '" + name + "'. Options are: " + strings.Join(r.Names(), ", "))
+			}
+			resolved = name
+		}
+		queue = append(queue, resolved)
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if remove[name] {
+			continue
+		}
+		remove[name] = true
+		for other, requires := range r.requires {
+			for _, required := range requires {
+				if required == name {
+					queue = append(queue, other)
+				}
+			}
+		}
+	}
+
+	order, err := r.sortByRequires(remove)
+	if err != nil {
+		return nil, err
+	}
+
+	// dependents first, and only what is there
+	var deletes []string
+	for i := len(order) - 1; i >= 0; i-- {
+		if isInstalled[order[i]] {
+			deletes = append(deletes, order[i])
+		}
+	}
+
+	return deletes, nil
+}
+
+// sortByRequires orders a set of addons so each one comes after the addons in the set it needs. It uses
+// Kahn's algorithm, taking the lowest name first so the order is the same every time
+func (r *AddonRegistry) sortByRequires(set map[string]bool) ([]string, error) {
+
+	waiting := map[string]int{}
+	for name := range set {
+		for _, required := range r.requires[name] {
+			if set[required] {
+				waiting[name]++
+			}
+		}
+	}
+	var ready, order []string
+	for name := range set {
+		if waiting[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+
+		for other := range set {
+			for _, required := range r.requires[other] {
+				if required == name {
+					waiting[other]--
+					if waiting[other] == 0 {
+						ready = append(ready, other)
+					}
+				}
+			}
+		}
+	}
+
+	if len(order) != len(set) {
+		var cycle []string
+		for name, count := range waiting {
+			if count > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, errors.New("Addon dependencies form a cycle between " + strings.Join(cycle, ", "))
+	}
+
+	return order, nil
+}
+
+// checkConflicts returns an error if two of the wanted addons, or a wanted and an installed addon, conflict.
+// Conflicts are checked both ways as the catalogue may only list them on one side
+func (r *AddonRegistry) checkConflicts(wanted map[string]bool, installed []string) error {
+
+	present := map[string]bool{}
+	for name := range wanted {
+		present[name] = true
+	}
+	for _, name := range installed {
+		present[name] = true
+	}
+
+	names := make([]string, 0, len(present))
+	for name := range present {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, conflict := range r.entries[name].Conflicts {
+			if resolved, ok := r.Resolve(conflict); ok {
+				conflict = resolved
+			}
+			if !present[conflict] || (!wanted[name] && !wanted[conflict]) {
+				continue
+			}
+			if wanted[name] && wanted[conflict] {
+				return errors.New("Addon " + name + " conflicts with " + conflict + ", they cannot be installed together")
+			}
+			return errors.New("Addon " + name + " conflicts with " + conflict + " which is installed")
+		}
+	}
+
+	return nil
+}
+
+// InstallAddonFromRegistry installs an addon from a registry after the addons it needs, followed by its
+// dependencies, waiting up to AddonInstallTimeout for each one, or until ctx is done. Addons which are already
+// installed are left as they are. values are optional Helm values, YAML or JSON, merged over the addon's
+// catalogue overrides. The addons it needs and its dependencies get their catalogue values. With values an error is returned if the addon is already installed,
+// as its values would not change
+func (s *Client) InstallAddonFromRegistry(ctx context.Context, clusterUUID string, registry *AddonRegistry, addonName string, values []byte) error {
+	Debug(1, "Entered InstallAddonFromRegistry for UUID "+clusterUUID+" addon "+addonName)
+
+	if clusterUUID == "" {
+		return errors.New("Cluster UUID is required")
+	}
+
+	// check the values before anything is sent
+	var overrides map[string]interface{}
+	if values != nil {
+		var err error
+		overrides, err = ParseAddonValues(values)
+		if err != nil {
+			return err
+		}
+	}
+
+	name, ok := registry.Resolve(addonName)
+	if !ok {
+		return errors.New("Unknown addon '" + addonName + "'. Options are: " + strings.Join(registry.Names(), ", "))
+	}
+
+	installedAddons, err := s.GetClusterInstalledAddons(clusterUUID)
+	if err != nil {
+		return err
+	}
+	var installed []string
+	for _, addon := range installedAddons.Results {
+		if addon.Name == name && overrides != nil {
+			return errors.New("Addon " + name + " is already installed, its values are not changed")
+		}
+		installed = append(installed, addon.Name)
+	}
+
+	order, err := registry.InstallOrder([]string{name}, installed)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range order {
+		if entry.Name == name {
+			entry.Overrides, err = MergeAddonValues(&entry, overrides)
+			if err != nil {
+				return err
+			}
+		}
+		err = s.installAddonEntry(ctx, clusterUUID, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// installAddonEntry installs one catalogue entry with installAddonBody, waiting up to AddonInstallTimeout for it
+func (s *Client) installAddonEntry(ctx context.Context, clusterUUID string, entry CatalogEntry) error {
+
+	// dependencies are installed as addons of their own
+	entry.Dependencies = nil
+	jsonBody, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	Debug(2, "Installing addon "+entry.Name)
+	Debug(3, string(jsonBody))
+
+	return s.installAddonBody(ctx, clusterUUID, entry.Name, jsonBody, AddonInstallTimeout)
+}
+
+// installAddonBody posts an addon unless it is already listed on the cluster, then waits for it with WaitForAddon.
+// An addon listed as failed is not posted again, WaitForAddon reports the failure
+func (s *Client) installAddonBody(ctx context.Context, clusterUUID string, addonName string, jsonBody []byte, timeout time.Duration) error {
+
+	_, err := s.GetClusterAddon(clusterUUID, addonName)
+	if errors.Is(err, ErrAddonNotInstalled) {
+		req, err := http.NewRequest("POST", s.BaseURL+"/v3/clusters/"+clusterUUID+"/addons/", bytes.NewBuffer(jsonBody))
+		if err != nil {
+			return err
+		}
+		_, err = s.doRequest(req)
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	_, err = s.WaitForAddon(ctx, clusterUUID, addonName, timeout)
+	return err
+}
+
+func appendMissing(list []string, value string) []string {
+	for _, item := range list {
+		if item == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,418 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCatalog is a cluster catalogue in the layout CCP returns
+func testCatalog() AddonsCatalogue {
+	return AddonsCatalogue{
+		"_ccp-istio-operator": {
+			Name:      "ccp-istio-operator",
+			URL:       "/opt/ccp/charts/ccp-istio-operator.tgz",
+			Conflicts: []string{"ccp-kubeflow", "ccp-harbor-operator"},
+			Dependencies: AddonsCatalogue{
+				"_ccp-istio": {Name: "ccp-istio-cr", URL: "/opt/ccp/charts/ccp-istio-cr.tgz"},
+			},
+		},
+		"_ccp-harbor-operator": {
+			Name:      "ccp-harbor-operator",
+			URL:       "/opt/ccp/charts/ccp-harbor-operator.tgz",
+			Conflicts: []string{"ccp-istio-operator"},
+			Dependencies: AddonsCatalogue{
+				"_ccp-harbor": {Name: "ccp-harbor-cr", URL: "/opt/ccp/charts/ccp-harbor-cr.tgz"},
+			},
+		},
+		"_ccp-monitor":          {Name: "ccp-monitor", URL: "/opt/ccp/charts/ccp-monitor.tgz"},
+		"_ccp-efk":              {Name: "ccp-efk", URL: "/opt/ccp/charts/ccp-efk.tgz"},
+		"_kubernetes-dashboard": {Name: "kubernetes-dashboard", URL: "/opt/ccp/charts/kubernetes-dashboard.tgz"},
+		"_ccp-kubeflow":         {Name: "ccp-kubeflow", URL: "/opt/ccp/charts/ccp-kubeflow.tgz"},
+	}
+}
+
+func testRegistry(t *testing.T) *AddonRegistry {
+	registry, err := NewAddonRegistry(testCatalog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+// addonServer is a CCP addons API for one cluster. Posted addons are listed with the status in status, INSTALLED
+// when there is none, and the version of their chart in versions. The list is returned pageSize addons at a time,
+// linking to the next page with an absolute URL on another host, or ?page=n when relative is set
+type addonServer struct {
+	mu        sync.Mutex
+	catalog   AddonsCatalogue
+	installed []Results
+	status    map[string]string
+	versions  map[string]string
+	pageSize  int
+	relative  bool
+	posts     []string
+	deletes   []string
+}
+
+func (a *addonServer) install(name, url, status string) *Results {
+	a.installed = append(a.installed, Results{Name: String(name), URL: String(url), AddonStatus: &Status{
+		Status:           String(status),
+		URLInstalled:     String(url),
+		VersionInstalled: String(a.versions[url]),
+	}})
+	return &a.installed[len(a.installed)-1]
+}
+
+func (a *addonServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/catalog"):
+		json.NewEncoder(w).Encode(a.catalog)
+
+	case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/addons/"):
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		size := a.pageSize
+		if size == 0 {
+			size = len(a.installed) + 1
+		}
+		start, end := (page-1)*size, page*size
+		if start > len(a.installed) {
+			start = len(a.installed)
+		}
+		if end > len(a.installed) {
+			end = len(a.installed)
+		}
+		list := struct {
+			Count   int       `json:"count"`
+			Next    *string   `json:"next"`
+			Results []Results `json:"results"`
+		}{Count: len(a.installed), Results: a.installed[start:end]}
+		if end < len(a.installed) {
+			list.Next = String("http://ccp.example.com" + r.URL.Path + "?page=" + strconv.Itoa(page+1))
+			if a.relative {
+				list.Next = String("?page=" + strconv.Itoa(page+1))
+			}
+		}
+		json.NewEncoder(w).Encode(list)
+
+	case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/addons/"):
+		var entry CatalogEntry
+		json.NewDecoder(r.Body).Decode(&entry)
+		status := a.status[entry.Name]
+		if status == "" {
+			status = "INSTALLED"
+		}
+		addon := a.install(entry.Name, entry.URL, status)
+		if entry.Overrides != "" {
+			addon.Overrides = String(entry.Overrides)
+			addon.AddonStatus.OverrideHash = String(strconv.Itoa(len(entry.Overrides)))
+		}
+		a.posts = append(a.posts, entry.Name)
+		w.WriteHeader(http.StatusCreated)
+
+	case r.Method == "DELETE" && strings.Contains(r.URL.Path, "/addons/"):
+		name := strings.Trim(r.URL.Path[strings.Index(r.URL.Path, "/addons/")+len("/addons/"):], "/")
+		for i, addon := range a.installed {
+			if *addon.Name == name {
+				a.installed = append(a.installed[:i], a.installed[i+1:]...)
+				a.deletes = append(a.deletes, name)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// fastAddonPolls makes addon waits poll every millisecond until the test ends
+func fastAddonPolls(t *testing.T) {
+	interval := AddonPollInterval
+	AddonPollInterval = time.Millisecond
+	t.Cleanup(func() { AddonPollInterval = interval })
+}
+
+func TestNewAddonRegistry(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		catalog AddonsCatalogue
+		err     string
+	}{
+		{"catalogue", testCatalog(), ""},
+		{"no name", AddonsCatalogue{"_x": {URL: "/opt/ccp/charts/x.tgz"}}, "has no name"},
+		{"two charts", AddonsCatalogue{
+			"_a": {Name: "a", URL: "/opt/ccp/charts/a.tgz", Dependencies: AddonsCatalogue{"_b": {Name: "b", URL: "/opt/ccp/charts/b.tgz"}}},
+			"_b": {Name: "b", URL: "/opt/ccp/charts/other.tgz"},
+		}, "different charts"},
+	}
+
+	for _, test := range tests {
+		_, err := NewAddonRegistry(test.catalog)
+		if test.err == "" && err != nil {
+			t.Errorf("%s: error %v", test.name, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestAddonRegistryResolve(t *testing.T) {
+
+	registry := testRegistry(t)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"ccp-monitor", "ccp-monitor"},
+		{"_ccp-monitor", "ccp-monitor"},
+		{"monitoring", "ccp-monitor"},
+		{"istio", "ccp-istio-operator"},
+		{"_ccp-istio", "ccp-istio-cr"},
+		{"ccp-istio", "ccp-istio-cr"},
+		{"hxcsi", ""}, // an alias for an addon which is not in the catalogue
+		{"nothing", ""},
+	}
+
+	for _, test := range tests {
+		got, ok := registry.Resolve(test.name)
+		if got != test.want || ok != (test.want != "") {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", test.name, got, ok, test.want)
+		}
+	}
+}
+
+func TestInstallOrder(t *testing.T) {
+
+	registry := testRegistry(t)
+
+	tests := []struct {
+		names     []string
+		installed []string
+		want      []string
+		err       string
+	}{
+		{[]string{"istio"}, nil, []string{"ccp-istio-operator", "ccp-istio-cr"}, ""},
+		{[]string{"ccp-istio-cr"}, nil, []string{"ccp-istio-operator", "ccp-istio-cr"}, ""},
+		{[]string{"monitoring", "logging", "dashboard"}, nil, []string{"ccp-efk", "ccp-monitor", "kubernetes-dashboard"}, ""},
+		{[]string{"harbor"}, []string{"ccp-monitor"}, []string{"ccp-harbor-operator", "ccp-harbor-cr"}, ""},
+		{[]string{"istio", "harbor"}, nil, nil, "cannot be installed together"},
+		{[]string{"kubeflow"}, []string{"ccp-istio-operator"}, nil, "which is installed"},
+		{[]string{"harbor"}, []string{"ccp-istio-operator"}, nil, "which is installed"},
+		{[]string{"nothing"}, nil, nil, "Unknown addon 'nothing'"},
+	}
+
+	for _, test := range tests {
+		order, err := registry.InstallOrder(test.names, test.installed)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("InstallOrder(%v, %v) error %v, want %q", test.names, test.installed, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("InstallOrder(%v, %v) error %v", test.names, test.installed, err)
+			continue
+		}
+		var names []string
+		for _, entry := range order {
+			names = append(names, entry.Name)
+			if entry.Namespace != DefaultAddonNamespace {
+				t.Errorf("InstallOrder entry %s namespace %q, want %q", entry.Name, entry.Namespace, DefaultAddonNamespace)
+			}
+		}
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("InstallOrder(%v, %v) = %v, want %v", test.names, test.installed, names, test.want)
+		}
+	}
+}
+
+func TestDeleteOrder(t *testing.T) {
+
+	registry := testRegistry(t)
+
+	tests := []struct {
+		names     []string
+		installed []string
+		want      []string
+		err       string
+	}{
+		{[]string{"istio"}, []string{"ccp-istio-operator", "ccp-istio-cr"}, []string{"ccp-istio-cr", "ccp-istio-operator"}, ""},
+		{[]string{"istio"}, []string{"ccp-istio-operator"}, []string{"ccp-istio-operator"}, ""},
+		{[]string{"ccp-istio-cr"}, []string{"ccp-istio-operator", "ccp-istio-cr"}, []string{"ccp-istio-cr"}, ""},
+		{[]string{"monitoring"}, []string{"ccp-efk"}, nil, ""},
+		{[]string{"custom-chart"}, []string{"custom-chart"}, []string{"custom-chart"}, ""},
+		{[]string{"nothing"}, nil, nil, "Unknown addon 'nothing'"},
+	}
+
+	for _, test := range tests {
+		order, err := registry.DeleteOrder(test.names, test.installed)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("DeleteOrder(%v, %v) error %v, want %q", test.names, test.installed, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("DeleteOrder(%v, %v) error %v", test.names, test.installed, err)
+			continue
+		}
+		if !reflect.DeepEqual(order, test.want) {
+			t.Errorf("DeleteOrder(%v, %v) = %v, want %v", test.names, test.installed, order, test.want)
+		}
+	}
+}
+
+func TestSortByRequires(t *testing.T) {
+
+	registry := &AddonRegistry{requires: map[string][]string{
+		"app":      {"database", "queue"},
+		"database": {"storage"},
+		"queue":    {"storage"},
+		"ping":     {"pong"},
+		"pong":     {"ping"},
+	}}
+
+	tests := []struct {
+		set  []string
+		want []string
+		err  string
+	}{
+		{[]string{"app", "database", "queue", "storage"}, []string{"storage", "database", "queue", "app"}, ""},
+		{[]string{"app", "queue"}, []string{"queue", "app"}, ""},
+		{[]string{"queue", "database"}, []string{"database", "queue"}, ""},
+		{[]string{"ping", "pong", "storage"}, nil, "cycle between ping, pong"},
+	}
+
+	for _, test := range tests {
+		set := map[string]bool{}
+		for _, name := range test.set {
+			set[name] = true
+		}
+		order, err := registry.sortByRequires(set)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("sortByRequires(%v) error %v, want %q", test.set, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(order, test.want) {
+			t.Errorf("sortByRequires(%v) = %v, %v, want %v", test.set, order, err, test.want)
+		}
+	}
+}
+
+func TestInstallAddonWrappers(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	tests := []struct {
+		name      string
+		install   func(s *Client, clusterUUID string) error
+		installed []string
+		want      []string
+	}{
+		{"istio", (*Client).InstallAddonIstio, nil, []string{"ccp-istio-operator", "ccp-istio-cr"}},
+		{"istio instance", (*Client).InstallAddonIstioInstance, []string{"ccp-istio-operator"}, []string{"ccp-istio-cr"}},
+		{"harbor", (*Client).InstallAddonHarbor, nil, []string{"ccp-harbor-operator", "ccp-harbor-cr"}},
+		{"monitoring", (*Client).InstallAddonMonitoring, nil, []string{"ccp-monitor"}},
+		{"logging", (*Client).InstallAddonLogging, []string{"ccp-efk"}, nil},
+		{"dashboard", (*Client).InstallAddonDashboard, nil, []string{"kubernetes-dashboard"}},
+		{"kubeflow", (*Client).InstallAddonKubeflow, nil, []string{"ccp-kubeflow"}},
+	}
+
+	for _, test := range tests {
+		server := &addonServer{catalog: testCatalog()}
+		for _, name := range test.installed {
+			server.install(name, "/opt/ccp/charts/"+name+".tgz", "INSTALLED")
+		}
+		client := newTestClient(t, server)
+
+		err := test.install(client, "cluster-1")
+		if err != nil {
+			t.Errorf("%s: error %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(server.posts, test.want) {
+			t.Errorf("%s: posted %v, want %v", test.name, server.posts, test.want)
+		}
+	}
+
+	// conflicts are refused before anything is posted
+	server := &addonServer{catalog: testCatalog()}
+	server.install("ccp-istio-operator", "/opt/ccp/charts/ccp-istio-operator.tgz", "INSTALLED")
+	client := newTestClient(t, server)
+	err := client.InstallAddonKubeflow("cluster-1")
+	if err == nil || len(server.posts) > 0 {
+		t.Errorf("InstallAddonKubeflow with istio installed: error %v, posted %v", err, server.posts)
+	}
+	err = client.InstallAddonHXCSI("cluster-1")
+	if err == nil || !strings.Contains(err.Error(), "Unknown addon") {
+		t.Errorf("InstallAddonHXCSI without it in the catalogue: error %v", err)
+	}
+}
+
+func TestInstallAddonTimeoutPerAddon(t *testing.T) {
+
+	fastAddonPolls(t)
+	timeout := AddonInstallTimeout
+	AddonInstallTimeout = 300 * time.Millisecond
+	t.Cleanup(func() { AddonInstallTimeout = timeout })
+
+	// each addon takes two thirds of the timeout, so istio takes longer than one timeout in all
+	server := &addonServer{catalog: testCatalog(), status: map[string]string{
+		"ccp-istio-operator": "INSTALLING",
+		"ccp-istio-cr":       "INSTALLING",
+	}}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r)
+		if r.Method != "POST" {
+			return
+		}
+		time.AfterFunc(200*time.Millisecond, func() {
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			for _, addon := range server.installed {
+				addon.AddonStatus.Status = String("INSTALLED")
+			}
+		})
+	}))
+
+	err := client.InstallAddonIstio("cluster-1")
+	if err != nil {
+		t.Fatalf("InstallAddonIstio error %v", err)
+	}
+	if want := []string{"ccp-istio-operator", "ccp-istio-cr"}; !reflect.DeepEqual(server.posts, want) {
+		t.Errorf("posted %v, want %v", server.posts, want)
+	}
+}
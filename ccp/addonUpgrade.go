@@ -0,0 +1,287 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrAddonNotInstalled is returned when an addon is not on the cluster
+var ErrAddonNotInstalled = errors.New("Addon is not installed")
+
+// AddonUpdate reports what UpgradeAddon or ReconfigureAddon did. Changed is false when the addon was already
+// in the desired state
+type AddonUpdate struct {
+	Name            string   `json:"name"`
+	Changed         bool     `json:"changed"`
+	Reason          string   `json:"reason,omitempty"`
+	OldVersion      string   `json:"oldVersion,omitempty"`
+	NewVersion      string   `json:"newVersion,omitempty"`
+	OldOverrideHash string   `json:"oldOverrideHash,omitempty"`
+	NewOverrideHash string   `json:"newOverrideHash,omitempty"`
+	Reinstalled     []string `json:"reinstalled,omitempty"` // installed addons which need this one, installed again with it
+}
+
+// GetClusterAddon returns an installed addon with its chart, overrides and status. The error wraps
+// ErrAddonNotInstalled if the addon is not on the cluster
+func (s *Client) GetClusterAddon(clusterUUID string, addonName string) (*Results, error) {
+	Debug(3, "GetClusterAddon for cluster "+clusterUUID+" addon "+addonName)
+
+	pages := s.addonPages(clusterUUID)
+	for {
+		var page struct {
+			Results []Results `json:"results"`
+		}
+		if !pages.next(&page) {
+			break
+		}
+		for i := range page.Results {
+			if page.Results[i].Name != nil && *page.Results[i].Name == addonName {
+				return &page.Results[i], nil
+			}
+		}
+	}
+	if pages.err != nil {
+		return nil, pages.err
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrAddonNotInstalled, addonName)
+}
+
+// UpgradeAddon moves an installed addon to the chart in the cluster catalogue, keeping its overrides. It is
+// needed when the installed chart is not the catalogue chart. CCP cannot update an addon in place, so the addon
+// is deleted and installed again, then UpgradeAddon waits for it to be installed and checks version if set. The
+// catalogue has no versions, so a version which is not installed while the catalogue chart is installed cannot
+// be reached and is an error, before anything is deleted. Installed addons which need it are reinstalled with it,
+// see reinstallAddon
+func (s *Client) UpgradeAddon(ctx context.Context, clusterUUID string, addonName string, version string) (*AddonUpdate, error) {
+	Debug(1, "Entered UpgradeAddon for UUID "+clusterUUID+" addon "+addonName)
+
+	registry, entry, installed, err := s.addonEntryAndState(clusterUUID, addonName)
+	if err != nil {
+		return nil, err
+	}
+	update := newAddonUpdate(entry.Name, installed)
+
+	switch {
+	case installed.AddonStatus != nil && installed.AddonStatus.URLInstalled != nil && *installed.AddonStatus.URLInstalled != entry.URL:
+		update.Reason = "chart " + *installed.AddonStatus.URLInstalled + " is installed, the catalogue has " + entry.URL
+	case version != "" && update.OldVersion != version:
+		// installing the same chart again would give the same version
+		return update, errors.New("Addon " + entry.Name + " version " + update.OldVersion + " is installed from the catalogue chart " +
+			entry.URL + ", version " + version + " is not in the catalogue")
+	default:
+		return update, nil
+	}
+
+	// keep the overrides the addon was installed with
+	if installed.Overrides != nil {
+		entry.Overrides = *installed.Overrides
+	}
+	if installed.OverrideFiles != nil {
+		entry.OverrideFiles = *installed.OverrideFiles
+	}
+
+	return s.reinstallAddon(ctx, clusterUUID, registry, *entry, update, version)
+}
+
+// ReconfigureAddon sets the Helm values of an installed addon, a YAML or JSON document merged over the
+// catalogue overrides as in InstallAddonWithOverrides. Nothing is done if the installed overrides already
+// have these values. Otherwise the addon is deleted and installed again with the same chart, and
+// ReconfigureAddon waits for it to be installed and checks the override hash changed
+func (s *Client) ReconfigureAddon(ctx context.Context, clusterUUID string, addonName string, values []byte) (*AddonUpdate, error) {
+	Debug(1, "Entered ReconfigureAddon for UUID "+clusterUUID+" addon "+addonName)
+
+	// check the values before anything is sent
+	overrides, err := ParseAddonValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, entry, installed, err := s.addonEntryAndState(clusterUUID, addonName)
+	if err != nil {
+		return nil, err
+	}
+	update := newAddonUpdate(entry.Name, installed)
+
+	entry.Overrides, err = MergeAddonValues(entry, overrides)
+	if err != nil {
+		return nil, err
+	}
+	wanted, err := ParseAddonValues([]byte(entry.Overrides))
+	if err != nil {
+		return nil, err
+	}
+	current := map[string]interface{}{}
+	if installed.Overrides != nil {
+		current, err = ParseAddonValues([]byte(*installed.Overrides))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if reflect.DeepEqual(current, wanted) {
+		return update, nil
+	}
+	update.Reason = "overrides changed"
+
+	// keep the chart the addon was installed with
+	if installed.AddonStatus != nil && installed.AddonStatus.URLInstalled != nil {
+		entry.URL = *installed.AddonStatus.URLInstalled
+	}
+
+	update, err = s.reinstallAddon(ctx, clusterUUID, registry, *entry, update, "")
+	if err != nil {
+		return update, err
+	}
+	if update.OldOverrideHash != "" && update.NewOverrideHash == update.OldOverrideHash {
+		return update, errors.New("Addon " + entry.Name + " was installed again but its override hash did not change")
+	}
+
+	return update, nil
+}
+
+// addonEntryAndState returns the cluster's addon registry and the catalogue entry and installed state of an addon
+func (s *Client) addonEntryAndState(clusterUUID string, addonName string) (*AddonRegistry, *CatalogEntry, *Results, error) {
+
+	if clusterUUID == "" {
+		return nil, nil, nil, errors.New("Cluster UUID is required")
+	}
+
+	registry, err := s.GetAddonRegistry(clusterUUID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	entry, ok := registry.Get(addonName)
+	if !ok {
+		return nil, nil, nil, errors.New("Unknown addon '" + addonName + "'")
+	}
+
+	installed, err := s.GetClusterAddon(clusterUUID, entry.Name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return registry, entry, installed, nil
+}
+
+func newAddonUpdate(name string, installed *Results) *AddonUpdate {
+
+	update := &AddonUpdate{Name: name}
+	if installed.AddonStatus != nil {
+		if installed.AddonStatus.VersionInstalled != nil {
+			update.OldVersion = *installed.AddonStatus.VersionInstalled
+		}
+		if installed.AddonStatus.OverrideHash != nil {
+			update.OldOverrideHash = *installed.AddonStatus.OverrideHash
+		}
+	}
+
+	return update
+}
+
+// reinstallAddon deletes an addon, waits for it to go and installs entry in its place. Installed addons which
+// need it, such as ccp-istio-cr for ccp-istio-operator, are deleted first with DeleteAddonFromRegistry and
+// installed again afterwards with the chart and overrides they had. If version is set it is checked once the
+// addon is installed
+func (s *Client) reinstallAddon(ctx context.Context, clusterUUID string, registry *AddonRegistry, entry CatalogEntry, update *AddonUpdate, version string) (*AddonUpdate, error) {
+	Debug(2, "Installing addon "+entry.Name+" again: "+update.Reason)
+
+	dependents, err := s.installedDependents(clusterUUID, registry, entry.Name)
+	if err != nil {
+		return update, err
+	}
+
+	update.Changed = true
+	_, err = s.DeleteAddonFromRegistry(ctx, clusterUUID, registry, entry.Name)
+	if err != nil {
+		return update, err
+	}
+
+	err = s.installAddonEntry(ctx, clusterUUID, entry)
+	if err != nil {
+		return update, err
+	}
+
+	for _, dependent := range dependents {
+		err = s.installAddonEntry(ctx, clusterUUID, dependent)
+		if err != nil {
+			return update, err
+		}
+		update.Reinstalled = append(update.Reinstalled, dependent.Name)
+	}
+
+	installed, err := s.GetClusterAddon(clusterUUID, entry.Name)
+	if err != nil {
+		return update, err
+	}
+	status := newAddonUpdate(entry.Name, installed)
+	update.NewVersion, update.NewOverrideHash = status.OldVersion, status.OldOverrideHash
+	if version != "" && update.NewVersion != version {
+		return update, errors.New("Addon " + entry.Name + " installed version " + update.NewVersion + ", not " + version)
+	}
+
+	return update, nil
+}
+
+// installedDependents returns the installed addons which need an addon, in the order to install them, as they are
+// installed: with their chart, overrides and override files
+func (s *Client) installedDependents(clusterUUID string, registry *AddonRegistry, addonName string) ([]CatalogEntry, error) {
+
+	installedAddons, err := s.GetClusterInstalledAddons(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+	var installed []string
+	for _, addon := range installedAddons.Results {
+		installed = append(installed, addon.Name)
+	}
+
+	order, err := registry.DeleteOrder([]string{addonName}, installed)
+	if err != nil {
+		return nil, err
+	}
+
+	// deleted dependents first, so installed the other way round
+	var dependents []CatalogEntry
+	for i := len(order) - 1; i >= 0; i-- {
+		if order[i] == addonName {
+			continue
+		}
+		entry, ok := registry.Get(order[i])
+		if !ok {
+			return nil, errors.New("Unknown addon '" + order[i] + "'")
+		}
+		current, err := s.GetClusterAddon(clusterUUID, entry.Name)
+		if err != nil {
+			return nil, err
+		}
+		if current.AddonStatus != nil && current.AddonStatus.URLInstalled != nil {
+			entry.URL = *current.AddonStatus.URLInstalled
+		}
+		if current.Overrides != nil {
+			entry.Overrides = *current.Overrides
+		}
+		if current.OverrideFiles != nil {
+			entry.OverrideFiles = *current.OverrideFiles
+		}
+		dependents = append(dependents, *entry)
+	}
+
+	return dependents, nil
+}
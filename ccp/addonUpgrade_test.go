@@ -0,0 +1,165 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetClusterAddon(t *testing.T) {
+
+	server := &addonServer{pageSize: 1}
+	server.install("ccp-monitor", "/opt/ccp/charts/ccp-monitor.tgz", "INSTALLED")
+	server.install("ccp-efk", "/opt/ccp/charts/ccp-efk.tgz", "INSTALLING")
+	client := newTestClient(t, server)
+
+	addon, err := client.GetClusterAddon("cluster-1", "ccp-efk")
+	if err != nil || *addon.AddonStatus.Status != "INSTALLING" {
+		t.Errorf("GetClusterAddon(ccp-efk) = %v, %v", addon, err)
+	}
+	_, err = client.GetClusterAddon("cluster-1", "ccp-kubeflow")
+	if !errors.Is(err, ErrAddonNotInstalled) {
+		t.Errorf("GetClusterAddon(ccp-kubeflow) error %v, want ErrAddonNotInstalled", err)
+	}
+}
+
+func TestUpgradeAddon(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	tests := []struct {
+		name      string
+		installed string // chart installed
+		version   string
+		changed   bool
+		err       string
+		deletes   []string
+	}{
+		{"up to date", "/opt/ccp/charts/ccp-monitor.tgz", "", false, "", nil},
+		{"up to date version", "/opt/ccp/charts/ccp-monitor.tgz", "1.0.0", false, "", nil},
+		{"new chart", "/opt/ccp/charts/ccp-monitor-0.9.tgz", "", true, "", []string{"ccp-monitor"}},
+		{"new chart version", "/opt/ccp/charts/ccp-monitor-0.9.tgz", "1.0.0", true, "", []string{"ccp-monitor"}},
+		{"new chart other version", "/opt/ccp/charts/ccp-monitor-0.9.tgz", "2.0.0", true, "not 2.0.0", []string{"ccp-monitor"}},
+		{"version not in catalogue", "/opt/ccp/charts/ccp-monitor.tgz", "2.0.0", false, "not in the catalogue", nil},
+	}
+
+	for _, test := range tests {
+		server := &addonServer{catalog: testCatalog(), versions: map[string]string{
+			"/opt/ccp/charts/ccp-monitor-0.9.tgz": "0.9.0",
+			"/opt/ccp/charts/ccp-monitor.tgz":     "1.0.0",
+		}}
+		server.install("ccp-monitor", test.installed, "INSTALLED").Overrides = String("retention: 7d\n")
+		client := newTestClient(t, server)
+
+		update, err := client.UpgradeAddon(context.Background(), "cluster-1", "monitoring", test.version)
+		if test.err == "" && err != nil {
+			t.Errorf("%s: error %v", test.name, err)
+			continue
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+		if update.Changed != test.changed {
+			t.Errorf("%s: changed %v, want %v", test.name, update.Changed, test.changed)
+		}
+		if !reflect.DeepEqual(server.deletes, test.deletes) {
+			t.Errorf("%s: deleted %v, want %v", test.name, server.deletes, test.deletes)
+		}
+		if test.changed {
+			addon := server.installed[len(server.installed)-1]
+			if *addon.URL != "/opt/ccp/charts/ccp-monitor.tgz" || addon.Overrides == nil || *addon.Overrides != "retention: 7d\n" {
+				t.Errorf("%s: installed %s with overrides %v, want the catalogue chart and the old overrides", test.name, *addon.URL, addon.Overrides)
+			}
+			if update.OldVersion != "0.9.0" || update.NewVersion != "1.0.0" {
+				t.Errorf("%s: version %s -> %s, want 0.9.0 -> 1.0.0", test.name, update.OldVersion, update.NewVersion)
+			}
+		}
+	}
+}
+
+func TestUpgradeAddonReinstallsDependents(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	server := &addonServer{catalog: testCatalog()}
+	server.install("ccp-istio-operator", "/opt/ccp/charts/ccp-istio-operator-old.tgz", "INSTALLED")
+	server.install("ccp-istio-cr", "/opt/ccp/charts/ccp-istio-cr-custom.tgz", "INSTALLED").Overrides = String("mtls: true\n")
+	server.install("ccp-monitor", "/opt/ccp/charts/ccp-monitor.tgz", "INSTALLED")
+	client := newTestClient(t, server)
+
+	update, err := client.UpgradeAddon(context.Background(), "cluster-1", "istio", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"ccp-istio-cr", "ccp-istio-operator"}; !reflect.DeepEqual(server.deletes, want) {
+		t.Errorf("deleted %v, want %v", server.deletes, want)
+	}
+	if want := []string{"ccp-istio-operator", "ccp-istio-cr"}; !reflect.DeepEqual(server.posts, want) {
+		t.Errorf("posted %v, want %v", server.posts, want)
+	}
+	if want := []string{"ccp-istio-cr"}; !reflect.DeepEqual(update.Reinstalled, want) {
+		t.Errorf("reinstalled %v, want %v", update.Reinstalled, want)
+	}
+
+	// the dependent keeps its own chart and overrides
+	cr, err := client.GetClusterAddon("cluster-1", "ccp-istio-cr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cr.URL != "/opt/ccp/charts/ccp-istio-cr-custom.tgz" || cr.Overrides == nil || *cr.Overrides != "mtls: true\n" {
+		t.Errorf("ccp-istio-cr installed again from %s with %v", *cr.URL, cr.Overrides)
+	}
+}
+
+func TestReconfigureAddon(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	tests := []struct {
+		name    string
+		values  string
+		changed bool
+	}{
+		{"same values", "retention: 7d\n", false},
+		{"same values json", `{"retention": "7d"}`, false},
+		{"new values", "retention: 30d\n", true},
+	}
+
+	for _, test := range tests {
+		server := &addonServer{catalog: testCatalog()}
+		addon := server.install("ccp-efk", "/opt/ccp/charts/ccp-efk.tgz", "INSTALLED")
+		addon.Overrides = String("retention: 7d\n")
+		addon.AddonStatus.OverrideHash = String("old")
+		client := newTestClient(t, server)
+
+		update, err := client.ReconfigureAddon(context.Background(), "cluster-1", "logging", []byte(test.values))
+		if err != nil {
+			t.Errorf("%s: error %v", test.name, err)
+			continue
+		}
+		if update.Changed != test.changed || (len(server.posts) > 0) != test.changed {
+			t.Errorf("%s: changed %v, posted %v, want %v", test.name, update.Changed, server.posts, test.changed)
+		}
+		if test.changed && (update.OldOverrideHash != "old" || update.NewOverrideHash == "old") {
+			t.Errorf("%s: override hash %s -> %s", test.name, update.OldOverrideHash, update.NewOverrideHash)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ClusterSpec is a desired cluster. It is the Cluster JSON with an optional list of addons. When Addons is
+// nil the addons of the cluster are left alone, otherwise addons not in the list are removed
+type ClusterSpec struct {
+	Cluster
+	Addons *[]string `json:"addons,omitempty"`
+}
+
+// ApplyAction is one thing ApplyClusterSpec did, or would need to do
+type ApplyAction struct {
+	Cluster string `json:"cluster"`
+	Action  string `json:"action"` // create, patch, scale, addpool, install, delete, skip
+	Detail  string `json:"detail"`
+}
+
+// patchableClusterFields are the Cluster fields CCP can change in place with PatchCluster
+var patchableClusterFields = []string{
+	"Description",
+	"LoadBalancerIPNum",
+	"NTPPools",
+	"NTPServers",
+	"RegistriesRootCA",
+	"RegistriesSelfSigned",
+	"RegistriesInsecure",
+	"DockerProxyHTTP",
+	"DockerProxyHTTPS",
+	"DockerNoProxy",
+}
+
+// ReadClusterSpecs reads the cluster specs at path with LoadClusterSpecWithOptions. If path is a directory every
+// .json, .yaml and .yml file in it is read
+func ReadClusterSpecs(path string, options SpecOptions) ([]ClusterSpec, error) {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		for _, pattern := range []string{"*.json", "*.yaml", "*.yml"} {
+			matches, err := filepath.Glob(filepath.Join(path, pattern))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+	}
+
+	var specs []ClusterSpec
+	for _, file := range files {
+		fileSpecs, err := LoadClusterSpecWithOptions(file, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range fileSpecs {
+			if spec.Name == nil || *spec.Name == "" {
+				return nil, errors.New(file + ": cluster name is required")
+			}
+		}
+		specs = append(specs, fileSpecs...)
+	}
+
+	return specs, nil
+}
+
+// ApplyClusterSpec converges a cluster to spec. A missing cluster is created with AddCluster and waited on,
+// changed fields are patched with PatchCluster, worker pools are scaled with ScaleCluster or added with AddNodePool,
+// a new Kubernetes version is upgraded to with UpgradeCluster and addons are installed or removed. Changes CCP
+// cannot make in place, and worker pools which are not in the spec, are reported as skip actions.
+// Running it again with the same spec makes no changes
+func (s *Client) ApplyClusterSpec(ctx context.Context, spec *ClusterSpec) ([]ApplyAction, error) {
+
+	if spec == nil || spec.Name == nil {
+		return nil, errors.New("Cluster spec with a name is required")
+	}
+	name := *spec.Name
+	Debug(1, "Entered ApplyClusterSpec for "+name)
+
+	var actions []ApplyAction
+	action := func(kind, detail string) {
+		Debug(2, "Apply "+name+": "+kind+" "+detail)
+		actions = append(actions, ApplyAction{Cluster: name, Action: kind, Detail: detail})
+	}
+
+	diff, err := s.DiffClusterSpec(&spec.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	clusterUUID := diff.UUID
+
+	if diff.Create {
+		created, err := s.AddCluster(&spec.Cluster)
+		if err != nil {
+			return actions, err
+		}
+		action("create", "cluster created")
+
+		_, err = s.WaitForClusterReady(ctx, *created.UUID)
+		if err != nil {
+			return actions, err
+		}
+		clusterUUID = *created.UUID
+	} else {
+		changeActions, err := s.applyChanges(ctx, diff)
+		for _, a := range changeActions {
+			action(a.Action, a.Detail)
+		}
+		if err != nil {
+			return actions, err
+		}
+	}
+
+	if spec.Addons != nil {
+		addonActions, err := s.applyAddons(ctx, clusterUUID, *spec.Addons)
+		for _, a := range addonActions {
+			action(a.Action, a.Detail)
+		}
+		if err != nil {
+			return actions, err
+		}
+	}
+
+	return actions, nil
+}
+
+// findClusterByName returns the named cluster, or nil if there is no cluster with that name
+func (s *Client) findClusterByName(name string) (*Cluster, error) {
+	clusters, err := s.GetClusters()
+	if err != nil {
+		return nil, err
+	}
+	for i := range clusters {
+		if clusters[i].Name != nil && *clusters[i].Name == name {
+			return &clusters[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// applyChanges patches the changed fields in one PatchCluster call, scales worker pools, adds missing pools and
+// then upgrades the cluster. Worker pools which are not in the spec are left alone and changes which need
+// recreation are skipped
+func (s *Client) applyChanges(ctx context.Context, diff *ClusterDiff) ([]ApplyAction, error) {
+
+	var actions []ApplyAction
+	var patch Cluster
+	var patched []string
+	var upgrades []ClusterChange
+
+	patchValue := reflect.ValueOf(&patch).Elem()
+
+	for _, change := range diff.Changes {
+		switch {
+		case change.Kind == ChangeRecreate:
+			actions = append(actions, ApplyAction{Action: "skip", Detail: change.Path + " changed, this needs the cluster to be recreated"})
+
+		case change.Kind == ChangeUpgrade:
+			upgrades = append(upgrades, change)
+
+		case change.Kind == ChangeUnmanaged:
+			actions = append(actions, ApplyAction{Action: "skip", Detail: "worker pool " + change.Pool + " is not in the spec, left alone"})
+
+		case change.Kind == ChangeScale:
+			size := change.New.(int64)
+			_, err := s.ScaleCluster(diff.UUID, change.Pool, int(size))
+			if err != nil {
+				return actions, err
+			}
+			actions = append(actions, ApplyAction{Action: "scale", Detail: change.Pool + " to " + strconv.FormatInt(size, 10)})
+
+		case change.Pool != "" && change.New != nil:
+			pool := change.New.(WorkerNodePool)
+			_, err := s.AddNodePool(diff.UUID, &pool)
+			if err != nil {
+				return actions, err
+			}
+			actions = append(actions, ApplyAction{Action: "addpool", Detail: change.Pool})
+
+		default:
+			for i := 0; i < patchValue.NumField(); i++ {
+				if jsonName(patchValue.Type().Field(i)) == change.Path {
+					field := patchValue.Field(i)
+					field.Set(reflect.New(field.Type().Elem()))
+					field.Elem().Set(reflect.ValueOf(change.New))
+				}
+			}
+			patched = append(patched, change.Path)
+		}
+	}
+
+	if len(patched) > 0 {
+		_, err := s.PatchCluster(&patch, diff.UUID)
+		if err != nil {
+			return actions, err
+		}
+		actions = append(actions, ApplyAction{Action: "patch", Detail: strings.Join(patched, ", ")})
+	}
+
+	if len(upgrades) > 0 {
+		template, err := upgradeTemplate(upgrades)
+		if err != nil {
+			actions = append(actions, ApplyAction{Action: "skip", Detail: err.Error()})
+			return actions, nil
+		}
+		_, err = s.UpgradeCluster(ctx, diff.UUID, template)
+		if err != nil {
+			return actions, err
+		}
+		actions = append(actions, ApplyAction{Action: "upgrade", Detail: "to " + template})
+	}
+
+	return actions, nil
+}
+
+// upgradeTemplate returns the one template the upgrade changes ask for. UpgradeCluster moves every pool to the
+// same template, so the spec must name it and not ask for another template or version
+func upgradeTemplate(upgrades []ClusterChange) (string, error) {
+
+	template := ""
+	var paths []string
+	for _, change := range upgrades {
+		paths = append(paths, change.Path)
+		if !strings.HasSuffix(change.Path, "template") {
+			continue
+		}
+		if template != "" && change.New != template {
+			return "", errors.New(strings.Join(paths, ", ") + " changed to different templates, upgrade them to one template")
+		}
+		template = change.New.(string)
+	}
+	if template == "" {
+		return "", errors.New(strings.Join(paths, ", ") + " changed, set master_group.template to the template to upgrade to")
+	}
+
+	version := GetKubeVerFromImage(template)
+	for _, change := range upgrades {
+		if strings.HasSuffix(change.Path, "kubernetes_version") && change.New != version {
+			return "", errors.New(change.Path + " is not " + version + ", the version of template " + template)
+		}
+	}
+
+	return template, nil
+}
+
+// applyAddons installs the wanted addons which are missing and deletes installed addons which are not wanted.
+// Deletes go first so an unwanted addon does not conflict with a wanted one
+func (s *Client) applyAddons(ctx context.Context, clusterUUID string, addons []string) ([]ApplyAction, error) {
+
+	var actions []ApplyAction
+
+	registry, err := s.GetAddonRegistry(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := s.GetClusterInstalledAddons(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the wanted addons with everything they need, checked for conflicts among themselves
+	order, err := registry.InstallOrder(addons, nil)
+	if err != nil {
+		return nil, errors.New("Addons in cluster spec: " + err.Error())
+	}
+	wanted := map[string]bool{}
+	for _, entry := range order {
+		wanted[entry.Name] = true
+	}
+
+	var names, unwanted []string
+	for _, addon := range installed.Results {
+		names = append(names, addon.Name)
+		if wanted[addon.Name] {
+			continue
+		}
+		if _, known := registry.Resolve(addon.Name); !known {
+			actions = append(actions, ApplyAction{Action: "skip", Detail: "addon " + addon.Name + " is not in the spec but is not in the addon catalogue either"})
+			continue
+		}
+		unwanted = append(unwanted, addon.Name)
+	}
+
+	deletes, err := registry.DeleteOrder(unwanted, names)
+	if err != nil {
+		return actions, err
+	}
+	for _, name := range deletes {
+		err = s.deleteAddonAndWait(ctx, clusterUUID, name)
+		if err != nil {
+			return actions, err
+		}
+		actions = append(actions, ApplyAction{Action: "delete", Detail: name})
+	}
+
+	// addons which could not be removed must not conflict with the ones to install, which is checked against
+	// what is installed now
+	results, err := s.InstallAddonsFromRegistry(ctx, clusterUUID, registry, addons)
+	for _, result := range results {
+		if result.Result == AddonResultInstalled {
+			actions = append(actions, ApplyAction{Action: "install", Detail: result.Name})
+		}
+	}
+	if err != nil {
+		return actions, errors.New("Addons in cluster spec: " + err.Error())
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,104 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// applyServer is a CCP with one cluster, liveCluster, which takes patches and pool sizes
+type applyServer struct {
+	mu      sync.Mutex
+	cluster *Cluster
+}
+
+func (a *applyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v3/clusters":
+		json.NewEncoder(w).Encode([]Cluster{*a.cluster})
+	case r.Method == "GET" && r.URL.Path == "/v3/clusters/uuid":
+		json.NewEncoder(w).Encode(a.cluster)
+	case r.Method == "PATCH" && r.URL.Path == "/v3/clusters/uuid/":
+		json.Unmarshal(body, a.cluster)
+		json.NewEncoder(w).Encode(a.cluster)
+	case r.Method == "PATCH" && strings.HasPrefix(r.URL.Path, "/v3/clusters/uuid/node-pools/"):
+		var scale WorkerNodePool
+		json.Unmarshal(body, &scale)
+		pool := findWorkerNodePool(a.cluster, *scale.Name)
+		pool.Size = scale.Size
+		json.NewEncoder(w).Encode(pool)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestApplyClusterSpecConverges(t *testing.T) {
+
+	client := newTestClient(t, &applyServer{cluster: liveCluster()})
+
+	spec := &ClusterSpec{Cluster: Cluster{
+		Name:           String("mycluster"),
+		Description:    String("new"),
+		SubnetUUID:     String("other-subnet"),
+		WorkerNodePool: &[]WorkerNodePool{{Name: String("workers"), Size: Int64(3)}},
+	}}
+
+	tests := [][]string{
+		{
+			"skip subnet_id changed, this needs the cluster to be recreated",
+			"scale workers to 3",
+			"skip worker pool gpu is not in the spec, left alone",
+			"patch description",
+		},
+		{
+			"skip subnet_id changed, this needs the cluster to be recreated",
+			"skip worker pool gpu is not in the spec, left alone",
+		},
+	}
+
+	for run, want := range tests {
+		actions, err := client.ApplyClusterSpec(context.Background(), spec)
+		if err != nil {
+			t.Fatalf("run %d: %v", run+1, err)
+		}
+		var got []string
+		for _, action := range actions {
+			got = append(got, action.Action+" "+action.Detail)
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("run %d: actions\n%s\nwant\n%s", run+1, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+
+	spec.SubnetUUID = nil
+	diff, err := client.DiffClusterSpec(&spec.Cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Empty() {
+		t.Errorf("diff after apply is not empty: %+v", diff.Changes)
+	}
+}
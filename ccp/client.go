@@ -32,6 +32,10 @@ type Client struct {
 	Password   string
 	BaseURL    string
 	XAuthToken string
+	// ProtectedClusters are cluster name patterns which DeleteCluster will refuse to delete
+	ProtectedClusters []string
+	// AllowedClusters are cluster name patterns which may be deleted even if they match ProtectedClusters
+	AllowedClusters []string
 }
 
 var jar, err = cookiejar.New(nil)
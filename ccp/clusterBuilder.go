@@ -0,0 +1,391 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"encoding/json"
+
+	"gopkg.in/validator.v2"
+)
+
+// Defaults ClusterBuilder uses for the values which are not set, when defaults are on
+const (
+	DefaultClusterType        = "vsphere"
+	DefaultIPAllocationMethod = "ccpnet"
+	DefaultNetworkPlugin      = "calico"
+	DefaultPodCIDR            = "192.168.0.0/16"
+	DefaultLoadBalancerIPNum  = 1
+	DefaultMasterPoolName     = "master-group"
+	DefaultMasterSize         = 1
+	DefaultMasterVCPUs        = 2
+	DefaultMasterMemory       = 16384 // MB
+	DefaultWorkerPoolName     = "node-pool"
+	DefaultWorkerSize         = 1
+	DefaultWorkerVCPUs        = 2
+	DefaultWorkerMemory       = 32768 // MB
+)
+
+// ClusterBuilder builds a Cluster to give to AddCluster. Start with NewClusterBuilder, or ClusterBuilderFrom to
+// start from an existing Cluster, call the setters and then Build. With defaults on, Build fills in every value
+// which is not set:
+//
+//	Type                  DefaultClusterType
+//	IPAllocationMethod    DefaultIPAllocationMethod
+//	LoadBalancerIPNum     DefaultLoadBalancerIPNum
+//	NetworkPlugin         DefaultNetworkPlugin with DefaultPodCIDR
+//	KubernetesVersion     from the master template, see GetKubeVerFromImage
+//	MasterNodePool        DefaultMasterPoolName, DefaultMasterSize, DefaultMasterVCPUs, DefaultMasterMemory
+//	WorkerNodePool        one pool, DefaultWorkerPoolName, DefaultWorkerSize
+//	each worker pool      DefaultWorkerVCPUs, DefaultWorkerMemory, and the template, SSH user and key of the masters
+//
+// Worker pools given with WorkerPool are never replaced, only their unset values are filled in
+type ClusterBuilder struct {
+	cluster  Cluster
+	defaults bool
+}
+
+// NewClusterBuilder starts an empty cluster with defaults on
+func NewClusterBuilder() *ClusterBuilder {
+	return &ClusterBuilder{defaults: true}
+}
+
+// ClusterBuilderFrom starts from a copy of cluster with defaults off, so Build only validates and normalizes
+func ClusterBuilderFrom(cluster *Cluster) *ClusterBuilder {
+	b := &ClusterBuilder{}
+	if cluster != nil {
+		// copy through JSON so the cluster given is not changed
+		jsonBody, err := json.Marshal(cluster)
+		if err == nil {
+			err = json.Unmarshal(jsonBody, &b.cluster)
+		}
+		if err != nil {
+			b.cluster = *cluster
+		}
+	}
+	return b
+}
+
+// Defaults turns the defaults on or off
+func (b *ClusterBuilder) Defaults(on bool) *ClusterBuilder {
+	b.defaults = on
+	return b
+}
+
+// Name sets the cluster name
+func (b *ClusterBuilder) Name(name string) *ClusterBuilder {
+	b.cluster.Name = String(name)
+	return b
+}
+
+// Description sets the cluster description
+func (b *ClusterBuilder) Description(description string) *ClusterBuilder {
+	b.cluster.Description = String(description)
+	return b
+}
+
+// KubernetesVersion sets the Kubernetes version, by default it comes from the master template
+func (b *ClusterBuilder) KubernetesVersion(version string) *ClusterBuilder {
+	b.cluster.KubernetesVersion = String(version)
+	return b
+}
+
+// Template sets the CCP image of the masters, which worker pools without a template use too
+func (b *ClusterBuilder) Template(template string) *ClusterBuilder {
+	b.masterNodePool().Template = String(template)
+	return b
+}
+
+// Provider sets the UUID of the infrastructure provider
+func (b *ClusterBuilder) Provider(providerUUID string) *ClusterBuilder {
+	b.cluster.InfraProviderUUID = String(providerUUID)
+	return b
+}
+
+// Subnet sets the UUID of the subnet the cluster uses
+func (b *ClusterBuilder) Subnet(subnetUUID string) *ClusterBuilder {
+	b.cluster.SubnetUUID = String(subnetUUID)
+	return b
+}
+
+// IPAllocationMethod sets how node IPs are allocated, e.g. ccpnet or dhcp
+func (b *ClusterBuilder) IPAllocationMethod(method string) *ClusterBuilder {
+	b.cluster.IPAllocationMethod = String(method)
+	return b
+}
+
+// LoadBalancers sets the number of load balancer IPs
+func (b *ClusterBuilder) LoadBalancers(count int64) *ClusterBuilder {
+	b.cluster.LoadBalancerIPNum = Int64(count)
+	return b
+}
+
+// Infra sets the vSphere datacenter, datastore, cluster and port groups
+func (b *ClusterBuilder) Infra(datacenter, datastore, vsphereCluster string, networks ...string) *ClusterBuilder {
+	infra := b.infra()
+	infra.Datacenter = String(datacenter)
+	infra.Datastore = String(datastore)
+	infra.Cluster = String(vsphereCluster)
+	infra.Networks = &networks
+	return b
+}
+
+// ResourcePool sets the vSphere resource pool
+func (b *ClusterBuilder) ResourcePool(resourcePool string) *ClusterBuilder {
+	b.infra().ResourcePool = String(resourcePool)
+	return b
+}
+
+// Masters sets the number of masters, 1 or 3
+func (b *ClusterBuilder) Masters(size int64) *ClusterBuilder {
+	b.masterNodePool().Size = Int64(size)
+	return b
+}
+
+// MasterResources sets the vCPUs and memory in MB of each master
+func (b *ClusterBuilder) MasterResources(vcpus, memory int64) *ClusterBuilder {
+	master := b.masterNodePool()
+	master.VCPUs = Int64(vcpus)
+	master.Memory = Int64(memory)
+	return b
+}
+
+// SSH sets the SSH user and public key of the masters, which worker pools without their own use too
+func (b *ClusterBuilder) SSH(user, key string) *ClusterBuilder {
+	master := b.masterNodePool()
+	master.SSHUser = String(user)
+	master.SSHKey = String(key)
+	return b
+}
+
+// WorkerPool adds a worker pool
+func (b *ClusterBuilder) WorkerPool(pool WorkerNodePool) *ClusterBuilder {
+	if b.cluster.WorkerNodePool == nil {
+		b.cluster.WorkerNodePool = &[]WorkerNodePool{}
+	}
+	*b.cluster.WorkerNodePool = append(*b.cluster.WorkerNodePool, pool)
+	return b
+}
+
+// Workers adds a worker pool with the default name, or sets the size of the first pool if there is one
+func (b *ClusterBuilder) Workers(size int64) *ClusterBuilder {
+	if b.cluster.WorkerNodePool == nil || len(*b.cluster.WorkerNodePool) == 0 {
+		return b.WorkerPool(WorkerNodePool{Name: String(DefaultWorkerPoolName), Size: Int64(size)})
+	}
+	(*b.cluster.WorkerNodePool)[0].Size = Int64(size)
+	return b
+}
+
+// NetworkPlugin sets the CNI and the pod CIDR
+func (b *ClusterBuilder) NetworkPlugin(name, podCIDR string) *ClusterBuilder {
+	b.cluster.NetworkPlugin = &NetworkPlugin{
+		Name:    String(name),
+		Details: &NetworkPluginDetails{PodCIDR: String(podCIDR)},
+	}
+	return b
+}
+
+// RootCARegistries sets the registries which use a root CA
+func (b *ClusterBuilder) RootCARegistries(registries ...string) *ClusterBuilder {
+	b.cluster.RegistriesRootCA = &registries
+	return b
+}
+
+// InsecureRegistries sets the registries which are used without TLS verification
+func (b *ClusterBuilder) InsecureRegistries(registries ...string) *ClusterBuilder {
+	b.cluster.RegistriesInsecure = &registries
+	return b
+}
+
+// SelfSignedRegistryCA sets the CA certificate of self signed registries
+func (b *ClusterBuilder) SelfSignedRegistryCA(cert string) *ClusterBuilder {
+	b.cluster.RegistriesSelfSigned = &RegistriesSelfSigned{Cert: String(cert)}
+	return b
+}
+
+// NTP sets the NTP pools and servers
+func (b *ClusterBuilder) NTP(pools []string, servers []string) *ClusterBuilder {
+	b.cluster.NTPPools = &pools
+	b.cluster.NTPServers = &servers
+	return b
+}
+
+// Proxy sets the Docker HTTP and HTTPS proxies and the hosts which are not proxied
+func (b *ClusterBuilder) Proxy(httpProxy, httpsProxy string, noProxy ...string) *ClusterBuilder {
+	b.cluster.DockerProxyHTTP = String(httpProxy)
+	b.cluster.DockerProxyHTTPS = String(httpsProxy)
+	b.cluster.DockerNoProxy = &noProxy
+	return b
+}
+
+func (b *ClusterBuilder) masterNodePool() *MasterNodePool {
+	if b.cluster.MasterNodePool == nil {
+		b.cluster.MasterNodePool = &MasterNodePool{}
+	}
+	return b.cluster.MasterNodePool
+}
+
+func (b *ClusterBuilder) infra() *Infra {
+	if b.cluster.Infra == nil {
+		b.cluster.Infra = &Infra{}
+	}
+	return b.cluster.Infra
+}
+
+// Build fills in the defaults when they are on, normalizes the cluster for CCP and validates it. Each call
+// returns a new Cluster
+func (b *ClusterBuilder) Build() (*Cluster, error) {
+
+	cluster := ClusterBuilderFrom(&b.cluster).cluster
+	if cluster.Name != nil {
+		Debug(2, "Building cluster "+*cluster.Name)
+	}
+
+	if b.defaults {
+		fillClusterDefaults(&cluster)
+	}
+	normalizeCluster(&cluster)
+
+	errs := validator.Validate(cluster)
+	if errs != nil {
+		Debug(1, "Errors validating Cluster struct with validator.Validate(): "+errs.Error())
+		return nil, errs
+	}
+
+	return &cluster, nil
+}
+
+// fillClusterDefaults sets the values which are not set, see ClusterBuilder
+func fillClusterDefaults(cluster *Cluster) {
+
+	if cluster.Type == nil {
+		cluster.Type = String(DefaultClusterType)
+	}
+	if cluster.IPAllocationMethod == nil {
+		cluster.IPAllocationMethod = String(DefaultIPAllocationMethod)
+	}
+	if cluster.LoadBalancerIPNum == nil {
+		cluster.LoadBalancerIPNum = Int64(DefaultLoadBalancerIPNum)
+	}
+	if cluster.NetworkPlugin == nil {
+		cluster.NetworkPlugin = &NetworkPlugin{
+			Name:    String(DefaultNetworkPlugin),
+			Details: &NetworkPluginDetails{PodCIDR: String(DefaultPodCIDR)},
+		}
+	}
+
+	if cluster.MasterNodePool == nil {
+		cluster.MasterNodePool = &MasterNodePool{}
+	}
+	master := cluster.MasterNodePool
+	if master.Name == nil {
+		master.Name = String(DefaultMasterPoolName)
+	}
+	if master.Size == nil {
+		master.Size = Int64(DefaultMasterSize)
+	}
+	if master.VCPUs == nil {
+		master.VCPUs = Int64(DefaultMasterVCPUs)
+	}
+	if master.Memory == nil {
+		master.Memory = Int64(DefaultMasterMemory)
+	}
+
+	if cluster.KubernetesVersion == nil && master.Template != nil {
+		if version := GetKubeVerFromImage(*master.Template); version != "" {
+			cluster.KubernetesVersion = String(version)
+		}
+	}
+	if master.KubernetesVersion == nil {
+		master.KubernetesVersion = cluster.KubernetesVersion
+	}
+
+	if cluster.WorkerNodePool == nil || len(*cluster.WorkerNodePool) == 0 {
+		cluster.WorkerNodePool = &[]WorkerNodePool{{Name: String(DefaultWorkerPoolName), Size: Int64(DefaultWorkerSize)}}
+	}
+	for i := range *cluster.WorkerNodePool {
+		pool := &(*cluster.WorkerNodePool)[i]
+		if pool.Name == nil {
+			pool.Name = String(DefaultWorkerPoolName)
+		}
+		if pool.Size == nil {
+			pool.Size = Int64(DefaultWorkerSize)
+		}
+		if pool.VCPUs == nil {
+			pool.VCPUs = Int64(DefaultWorkerVCPUs)
+		}
+		if pool.Memory == nil {
+			pool.Memory = Int64(DefaultWorkerMemory)
+		}
+		if pool.Template == nil {
+			pool.Template = master.Template
+		}
+		if pool.SSHUser == nil {
+			pool.SSHUser = master.SSHUser
+		}
+		if pool.SSHKey == nil {
+			pool.SSHKey = master.SSHKey
+		}
+		if pool.KubernetesVersion == nil {
+			pool.KubernetesVersion = cluster.KubernetesVersion
+		}
+	}
+}
+
+// normalizeCluster makes the changes CCP needs before a cluster is sent
+func normalizeCluster(cluster *Cluster) {
+
+	// https://stackoverflow.com/questions/44320960/omitempty-doesnt-omit-interface-nil-values-in-json
+	// An empty slice is not nil, so omitempty won't omit it when we marshal and CCP gets nodes: [] or null
+	// which it doesn't like. The Terraform provider creates zero sized arrays, so set them to nil
+	if cluster.MasterNodePool != nil && cluster.MasterNodePool.Nodes != nil && len(*cluster.MasterNodePool.Nodes) == 0 {
+		cluster.MasterNodePool.Nodes = nil
+	}
+	if cluster.WorkerNodePool != nil {
+		for i := range *cluster.WorkerNodePool {
+			pool := &(*cluster.WorkerNodePool)[i]
+			if pool.Nodes != nil && len(*pool.Nodes) == 0 {
+				pool.Nodes = nil
+			}
+		}
+	}
+	for _, list := range []**[]string{
+		&cluster.NTPPools,
+		&cluster.NTPServers,
+		&cluster.DockerNoProxy,
+		&cluster.RegistriesRootCA,
+		&cluster.RegistriesInsecure,
+	} {
+		if *list != nil && len(**list) == 0 {
+			*list = nil
+		}
+	}
+
+	// CCP complains when networks is missing for contiv-aci even though it isn't used, and an empty slice
+	// must be sent rather than null. It also wants load_balancer_num although the ACI CNI doesn't use it.
+	// Values which are already set are kept
+	// https://apoorvam.github.io/blog/2017/golang-json-marshal-slice-as-empty-array-not-null
+	if cluster.NetworkPlugin != nil && cluster.NetworkPlugin.Name != nil && *cluster.NetworkPlugin.Name == "contiv-aci" {
+		if cluster.Infra == nil {
+			cluster.Infra = &Infra{}
+		}
+		if cluster.Infra.Networks == nil {
+			cluster.Infra.Networks = &[]string{}
+		}
+		if cluster.LoadBalancerIPNum == nil {
+			cluster.LoadBalancerIPNum = Int64(1)
+		}
+	}
+}
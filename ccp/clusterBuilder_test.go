@@ -0,0 +1,79 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeClusterContivACI(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		infra         *Infra
+		loadBalancers *int64
+		networks      []string
+		want          int64
+	}{
+		{"no infra", nil, nil, []string{}, 1},
+		{"no networks", &Infra{}, nil, []string{}, 1},
+		{"networks set", &Infra{Networks: &[]string{"vm-network"}}, Int64(3), []string{"vm-network"}, 3},
+		{"empty networks", &Infra{Networks: &[]string{}}, nil, []string{}, 1},
+	}
+
+	for _, test := range tests {
+		cluster := Cluster{
+			NetworkPlugin:     &NetworkPlugin{Name: String("contiv-aci")},
+			Infra:             test.infra,
+			LoadBalancerIPNum: test.loadBalancers,
+		}
+		normalizeCluster(&cluster)
+		if cluster.Infra == nil || cluster.Infra.Networks == nil || !reflect.DeepEqual(*cluster.Infra.Networks, test.networks) {
+			t.Errorf("%s: networks %v, want %v", test.name, cluster.Infra, test.networks)
+		}
+		if cluster.LoadBalancerIPNum == nil || *cluster.LoadBalancerIPNum != test.want {
+			t.Errorf("%s: load balancers %v, want %d", test.name, cluster.LoadBalancerIPNum, test.want)
+		}
+	}
+
+	// other network plugins are left alone
+	cluster := Cluster{NetworkPlugin: &NetworkPlugin{Name: String("calico")}}
+	normalizeCluster(&cluster)
+	if cluster.Infra != nil || cluster.LoadBalancerIPNum != nil {
+		t.Errorf("calico cluster was changed: %v %v", cluster.Infra, cluster.LoadBalancerIPNum)
+	}
+}
+
+func TestClusterBuilderFromKeepsNetworks(t *testing.T) {
+
+	networks := []string{"vm-network"}
+	original := &Cluster{
+		Name:          String("aci"),
+		NetworkPlugin: &NetworkPlugin{Name: String("contiv-aci")},
+		Infra:         &Infra{Networks: &networks},
+	}
+
+	built := ClusterBuilderFrom(original).cluster
+	normalizeCluster(&built)
+
+	if !reflect.DeepEqual(*built.Infra.Networks, networks) {
+		t.Errorf("networks = %v, want %v", *built.Infra.Networks, networks)
+	}
+	if original.LoadBalancerIPNum != nil {
+		t.Error("the cluster given was changed")
+	}
+}
@@ -0,0 +1,782 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SpecError is a problem in a cluster spec file. Line and Column are 1 based and 0 when not known
+type SpecError struct {
+	File     string
+	Document int // 1 based document in the file
+	Line     int
+	Column   int
+	Path     string // JSON path of the field, e.g. node_groups[0].size
+	Message  string
+}
+
+func (e *SpecError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.File)
+	if e.Line > 0 {
+		b.WriteString(":" + strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			b.WriteString(":" + strconv.Itoa(e.Column))
+		}
+	}
+	if e.Document > 1 {
+		b.WriteString(" (document " + strconv.Itoa(e.Document) + ")")
+	}
+	b.WriteString(": ")
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// SpecErrors are all of the problems found in a cluster spec file
+type SpecErrors []*SpecError
+
+func (e SpecErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// LoadClusterSpec reads the cluster specs in a JSON or YAML file. YAML is used for .yaml and .yml files,
+// JSON for .json files, and otherwise the content decides. A file can hold several specs: YAML documents
+// separated by ---, JSON objects one after another, or a JSON or YAML list. Problems are returned as
+// SpecErrors with the line and column of each
+func LoadClusterSpec(path string) ([]ClusterSpec, error) {
+	Debug(1, "Entered LoadClusterSpec for "+path)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseClusterSpec(path, data)
+}
+
+// ParseClusterSpec parses the cluster specs in data, name is the file name used for the format and in errors
+func ParseClusterSpec(name string, data []byte) ([]ClusterSpec, error) {
+	return parseClusterSpec(name, data, false)
+}
+
+// LoadClusterSpecStrict is LoadClusterSpec which also rejects fields that are not part of a cluster spec,
+// suggesting the field that was probably meant
+func LoadClusterSpecStrict(path string) ([]ClusterSpec, error) {
+	Debug(1, "Entered LoadClusterSpecStrict for "+path)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseClusterSpecStrict(path, data)
+}
+
+// ParseClusterSpecStrict is ParseClusterSpec which also rejects unknown fields
+func ParseClusterSpecStrict(name string, data []byte) ([]ClusterSpec, error) {
+	return parseClusterSpec(name, data, true)
+}
+
+func parseClusterSpec(name string, data []byte, strict bool) ([]ClusterSpec, error) {
+
+	documents, err := parseSpecDocuments(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeClusterSpecs(name, documents, strict)
+}
+
+// decodeClusterSpecs checks parsed documents against ClusterSpec, collecting the problems in all of them
+func decodeClusterSpecs(name string, documents []*specNode, strict bool) ([]ClusterSpec, error) {
+
+	var specs []ClusterSpec
+	var problems SpecErrors
+	for i, document := range documents {
+		decoder := specDecoder{file: name, document: i + 1, strict: strict}
+		var spec ClusterSpec
+		if decoder.decode(document, &spec) {
+			specs = append(specs, spec)
+		}
+		problems = append(problems, decoder.problems...)
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	return specs, nil
+}
+
+// parseSpecDocuments reads the JSON or YAML documents in data. A list at the top level is a list of documents
+func parseSpecDocuments(name string, data []byte) ([]*specNode, error) {
+
+	var documents []*specNode
+	var err error
+
+	if isYAMLSpec(name, data) {
+		documents, err = parseYAMLSpec(name, data)
+	} else {
+		documents, err = parseJSONSpec(name, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(documents) == 1 && documents[0].Kind == specArray {
+		documents = documents[0].Items
+	}
+	return documents, nil
+}
+
+// isYAMLSpec decides the format by the file extension, otherwise JSON starts with { or [
+func isYAMLSpec(name string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return true
+	case ".json":
+		return false
+	}
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[')
+}
+
+type specKind int
+
+const (
+	specNull specKind = iota
+	specObject
+	specArray
+	specString
+	specNumber
+	specBool
+)
+
+func (k specKind) String() string {
+	return [...]string{"null", "object", "list", "string", "number", "boolean"}[k]
+}
+
+// specNode is a value read from a JSON or YAML spec, with where it is in the file
+type specNode struct {
+	Kind   specKind
+	Line   int
+	Column int
+	Fields []specField // specObject, in file order
+	Items  []*specNode // specArray
+	Value  interface{} // string, json.Number or bool
+	Text   string      // the YAML scalar as written, so 1.16 can be used for a string
+	YAML   bool
+}
+
+type specField struct {
+	Key    string
+	Line   int
+	Column int
+	Value  *specNode
+}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// parseYAMLSpec reads every YAML document in data
+func parseYAMLSpec(name string, data []byte) ([]*specNode, error) {
+
+	var documents []*specNode
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for document := 1; ; document++ {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			specErr := &SpecError{File: name, Document: document, Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+			if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+				specErr.Line, _ = strconv.Atoi(match[1])
+				specErr.Message = strings.TrimPrefix(specErr.Message, match[0]+": ")
+			}
+			return nil, SpecErrors{specErr}
+		}
+		if len(node.Content) == 0 {
+			continue // empty document
+		}
+		converted, err := yamlSpecNode(node.Content[0])
+		if specErr, ok := err.(*SpecError); ok {
+			specErr.File = name
+			specErr.Document = document
+			return nil, SpecErrors{specErr}
+		}
+		if err != nil {
+			return nil, SpecErrors{{File: name, Document: document, Line: node.Line, Column: node.Column, Message: err.Error()}}
+		}
+		documents = append(documents, converted)
+	}
+
+	return documents, nil
+}
+
+// maxYAMLAliasNodes is how many nodes aliases can add to a document, so a small file which nests aliases
+// cannot expand to more than fits in memory
+const maxYAMLAliasNodes = 10000
+
+// yamlAliases tracks the aliases being followed in a YAML document, so an alias inside its own anchor and
+// aliases which expand too far are errors rather than endless recursion
+type yamlAliases struct {
+	following map[*yaml.Node]bool // anchors of the aliases being followed
+	first     *yaml.Node          // outermost alias being followed
+	expanded  int                 // nodes added by aliases
+}
+
+// enter starts following alias
+func (a *yamlAliases) enter(alias *yaml.Node) *SpecError {
+	if a.following[alias.Alias] {
+		return &SpecError{Line: alias.Line, Column: alias.Column, Message: "alias *" + alias.Value + " is inside its own anchor"}
+	}
+	if a.following == nil {
+		a.following = map[*yaml.Node]bool{}
+	}
+	if len(a.following) == 0 {
+		a.first = alias
+	}
+	a.following[alias.Alias] = true
+	return nil
+}
+
+// leave stops following alias
+func (a *yamlAliases) leave(alias *yaml.Node) {
+	delete(a.following, alias.Alias)
+}
+
+// add counts a node read while following an alias
+func (a *yamlAliases) add() *SpecError {
+	if len(a.following) == 0 {
+		return nil
+	}
+	a.expanded++
+	if a.expanded > maxYAMLAliasNodes {
+		return &SpecError{Line: a.first.Line, Column: a.first.Column, Message: "aliases expand to more than " + strconv.Itoa(maxYAMLAliasNodes) + " values"}
+	}
+	return nil
+}
+
+func yamlSpecNode(node *yaml.Node) (*specNode, error) {
+	return (&yamlAliases{}).specNode(node)
+}
+
+func (a *yamlAliases) specNode(node *yaml.Node) (*specNode, error) {
+
+	if node.Kind == yaml.AliasNode {
+		if err := a.enter(node); err != nil {
+			return nil, err
+		}
+		defer a.leave(node)
+		return a.specNode(node.Alias)
+	}
+	if err := a.add(); err != nil {
+		return nil, err
+	}
+
+	converted := &specNode{Line: node.Line, Column: node.Column, YAML: true}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		converted.Kind = specObject
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			value, err := a.specNode(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			converted.Fields = append(converted.Fields, specField{Key: key.Value, Line: key.Line, Column: key.Column, Value: value})
+		}
+	case yaml.SequenceNode:
+		converted.Kind = specArray
+		for _, item := range node.Content {
+			value, err := a.specNode(item)
+			if err != nil {
+				return nil, err
+			}
+			converted.Items = append(converted.Items, value)
+		}
+	case yaml.ScalarNode:
+		converted.Text = node.Value
+		switch node.ShortTag() {
+		case "!!null":
+			converted.Kind = specNull
+		case "!!bool":
+			var value bool
+			if err := node.Decode(&value); err != nil {
+				return nil, err
+			}
+			converted.Kind = specBool
+			converted.Value = value
+		case "!!int":
+			var value int64
+			if err := node.Decode(&value); err != nil {
+				return nil, err
+			}
+			converted.Kind = specNumber
+			converted.Value = json.Number(strconv.FormatInt(value, 10))
+		case "!!float":
+			var value float64
+			if err := node.Decode(&value); err != nil {
+				return nil, err
+			}
+			converted.Kind = specNumber
+			converted.Value = json.Number(strconv.FormatFloat(value, 'g', -1, 64))
+		default:
+			converted.Kind = specString
+			converted.Value = node.Value
+		}
+	default:
+		return nil, errors.New("unsupported YAML node")
+	}
+
+	return converted, nil
+}
+
+// jsonSpecReader reads JSON tokens keeping track of where each one starts. The line and column reached are kept
+// so the data is scanned once however many tokens there are
+type jsonSpecReader struct {
+	name     string
+	data     []byte
+	decoder  *json.Decoder
+	document int
+	offset   int64 // offset of line and column
+	line     int
+	column   int
+}
+
+// lineColumn returns the line and column of offset. Offsets usually only move forward, from the offset asked for
+// last, an earlier one is counted again from the start
+func (r *jsonSpecReader) lineColumn(offset int64) (int, int) {
+	if offset > int64(len(r.data)) {
+		offset = int64(len(r.data))
+	}
+	if r.line == 0 || offset < r.offset {
+		r.offset, r.line, r.column = 0, 1, 1
+	}
+	for _, c := range r.data[r.offset:offset] {
+		if c == '\n' {
+			r.line++
+			r.column = 1
+		} else {
+			r.column++
+		}
+	}
+	r.offset = offset
+	return r.line, r.column
+}
+
+func (r *jsonSpecReader) next() (json.Token, int, int, error) {
+
+	// tokens start after any whitespace and the , or : the decoder skips
+	start := r.decoder.InputOffset()
+	for start < int64(len(r.data)) && strings.ContainsRune(" \t\r\n,:", rune(r.data[start])) {
+		start++
+	}
+
+	token, err := r.decoder.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, 0, 0, err
+		}
+		specErr := &SpecError{File: r.name, Document: r.document, Message: err.Error()}
+		var syntaxErr *json.SyntaxError
+		switch {
+		case err == io.ErrUnexpectedEOF:
+			specErr.Line, specErr.Column = r.lineColumn(start)
+			specErr.Message = "unexpected end of file"
+		case errors.As(err, &syntaxErr) && syntaxErr.Offset >= int64(len(r.data)):
+			specErr.Line, specErr.Column = r.lineColumn(int64(len(r.data)))
+			specErr.Message = "unexpected end of file"
+		case errors.As(err, &syntaxErr) && syntaxErr.Offset > 0:
+			// the offset is just past the character that was not expected
+			specErr.Line, specErr.Column = r.lineColumn(syntaxErr.Offset - 1)
+		default:
+			specErr.Line, specErr.Column = r.lineColumn(start)
+		}
+		return nil, 0, 0, SpecErrors{specErr}
+	}
+
+	line, column := r.lineColumn(start)
+	return token, line, column, nil
+}
+
+func (r *jsonSpecReader) value(token json.Token, line, column int) (*specNode, error) {
+
+	node := &specNode{Line: line, Column: column}
+
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			node.Kind = specObject
+			for r.decoder.More() {
+				key, keyLine, keyColumn, err := r.next()
+				if err != nil {
+					return nil, err
+				}
+				valueToken, valueLine, valueColumn, err := r.next()
+				if err != nil {
+					return nil, err
+				}
+				value, err := r.value(valueToken, valueLine, valueColumn)
+				if err != nil {
+					return nil, err
+				}
+				node.Fields = append(node.Fields, specField{Key: key.(string), Line: keyLine, Column: keyColumn, Value: value})
+			}
+		case '[':
+			node.Kind = specArray
+			for r.decoder.More() {
+				itemToken, itemLine, itemColumn, err := r.next()
+				if err != nil {
+					return nil, err
+				}
+				item, err := r.value(itemToken, itemLine, itemColumn)
+				if err != nil {
+					return nil, err
+				}
+				node.Items = append(node.Items, item)
+			}
+		}
+		// closing } or ]
+		if _, _, _, err := r.next(); err != nil {
+			if err == io.EOF {
+				return nil, SpecErrors{{File: r.name, Document: r.document, Line: line, Column: column, Message: "unexpected end of file"}}
+			}
+			return nil, err
+		}
+	case string:
+		node.Kind = specString
+		node.Value = t
+	case json.Number:
+		node.Kind = specNumber
+		node.Value = t
+	case bool:
+		node.Kind = specBool
+		node.Value = t
+	case nil:
+		node.Kind = specNull
+	}
+
+	return node, nil
+}
+
+// parseJSONSpec reads every JSON value in data
+func parseJSONSpec(name string, data []byte) ([]*specNode, error) {
+
+	reader := jsonSpecReader{name: name, data: data, decoder: json.NewDecoder(bytes.NewReader(data))}
+	reader.decoder.UseNumber()
+
+	var documents []*specNode
+	for {
+		reader.document = len(documents) + 1
+		token, line, column, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		node, err := reader.value(token, line, column)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, node)
+	}
+
+	return documents, nil
+}
+
+// specDecoder checks a spec against the Go types and builds JSON for them, collecting the problems it finds.
+// In strict mode fields which are not in the Go types are problems too
+type specDecoder struct {
+	file     string
+	document int
+	strict   bool
+	problems SpecErrors
+}
+
+func (d *specDecoder) problem(line, column int, path string, message string) {
+	d.problems = append(d.problems, &SpecError{File: d.file, Document: d.document, Line: line, Column: column, Path: path, Message: message})
+}
+
+// decode fills out, a pointer to a struct, from node. It returns false if there were problems
+func (d *specDecoder) decode(node *specNode, out interface{}) bool {
+
+	value := d.convert(node, reflect.TypeOf(out), "")
+	if len(d.problems) > 0 {
+		return false
+	}
+
+	jsonBody, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(jsonBody, out)
+	}
+	if err != nil {
+		d.problem(node.Line, node.Column, "", err.Error())
+		return false
+	}
+	return true
+}
+
+// convert turns node into a value json.Marshal gives back as JSON for t, reporting values of the wrong type
+func (d *specDecoder) convert(node *specNode, t reflect.Type, path string) interface{} {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if node.Kind == specNull {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != specObject {
+			d.problem(node.Line, node.Column, path, "expected an object, found a "+node.Kind.String())
+			return nil
+		}
+		fields := specStructFields(t)
+		object := map[string]interface{}{}
+		for _, field := range node.Fields {
+			structField, ok := fields[field.Key]
+			if !ok {
+				if d.strict {
+					message := "unknown field \"" + field.Key + "\""
+					if suggestion := suggestSpecField(field.Key, fields); suggestion != "" {
+						message += ", did you mean \"" + suggestion + "\"?"
+					}
+					d.problem(field.Line, field.Column, joinSpecPath(path, field.Key), message)
+				}
+				continue // otherwise unknown fields are dropped, as json.Unmarshal does
+			}
+			object[field.Key] = d.convert(field.Value, structField.Type, joinSpecPath(path, field.Key))
+		}
+		return object
+
+	case reflect.Slice:
+		if node.Kind != specArray {
+			d.problem(node.Line, node.Column, path, "expected a list, found a "+node.Kind.String())
+			return nil
+		}
+		list := make([]interface{}, len(node.Items))
+		for i, item := range node.Items {
+			list[i] = d.convert(item, t.Elem(), path+"["+strconv.Itoa(i)+"]")
+		}
+		return list
+
+	case reflect.String:
+		if node.Kind == specString {
+			return node.Value
+		}
+		if node.YAML && node.Kind != specObject && node.Kind != specArray {
+			return node.Text // unquoted YAML like kubernetes_version: 1.16
+		}
+		d.problem(node.Line, node.Column, path, "expected a string, found a "+node.Kind.String())
+
+	case reflect.Bool:
+		if node.Kind == specBool {
+			return node.Value
+		}
+		d.problem(node.Line, node.Column, path, "expected true or false, found a "+node.Kind.String())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if node.Kind == specNumber {
+			if _, err := node.Value.(json.Number).Int64(); err == nil {
+				return node.Value
+			}
+			d.problem(node.Line, node.Column, path, "expected a whole number, found "+node.Value.(json.Number).String())
+			return nil
+		}
+		d.problem(node.Line, node.Column, path, "expected a number, found a "+node.Kind.String())
+
+	case reflect.Float32, reflect.Float64:
+		if node.Kind == specNumber {
+			return node.Value
+		}
+		d.problem(node.Line, node.Column, path, "expected a number, found a "+node.Kind.String())
+
+	default:
+		return d.generic(node)
+	}
+
+	return nil
+}
+
+// generic converts node without a Go type to check it against
+func (d *specDecoder) generic(node *specNode) interface{} {
+	switch node.Kind {
+	case specObject:
+		object := map[string]interface{}{}
+		for _, field := range node.Fields {
+			object[field.Key] = d.generic(field.Value)
+		}
+		return object
+	case specArray:
+		list := make([]interface{}, len(node.Items))
+		for i, item := range node.Items {
+			list[i] = d.generic(item)
+		}
+		return list
+	}
+	return node.Value
+}
+
+// specStructFields maps the JSON names of a struct to its fields, including the fields of embedded structs
+func specStructFields(t reflect.Type) map[string]reflect.StructField {
+
+	fields := map[string]reflect.StructField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for name, embedded := range specStructFields(field.Type) {
+				fields[name] = embedded
+			}
+			continue
+		}
+		name := jsonName(field)
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		fields[name] = field
+	}
+
+	return fields
+}
+
+// suggestSpecField finds the field name that was probably meant for an unknown key. Names are compared
+// ignoring case, _ and -, then by edit distance, then by prefix. If nothing is close the fields of nested
+// objects are searched, e.g. datastore gives vsphere_infra.datastore
+func suggestSpecField(key string, fields map[string]reflect.StructField) string {
+
+	normalKey := normalSpecName(key)
+	best := ""
+	bestDistance := len(normalKey)/3 + 1
+
+	for name := range fields {
+		normalName := normalSpecName(name)
+		if normalName == normalKey {
+			return name
+		}
+		distance := editDistance(normalKey, normalName)
+		if distance < bestDistance || (distance == bestDistance && best != "" && name < best) {
+			best = name
+			bestDistance = distance
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	for name := range fields {
+		normalName := normalSpecName(name)
+		if len(normalKey) >= 4 && (strings.HasPrefix(normalName, normalKey) || strings.HasPrefix(normalKey, normalName)) {
+			if best == "" || name < best {
+				best = name
+			}
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	for name, field := range fields {
+		t := field.Type
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		prefix := name + "."
+		if t.Kind() == reflect.Slice {
+			prefix = name + "[]."
+			t = t.Elem()
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+		}
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+		for nested := range specStructFields(t) {
+			if normalSpecName(nested) == normalKey && (best == "" || prefix+nested < best) {
+				best = prefix + nested
+			}
+		}
+	}
+	return best
+}
+
+func normalSpecName(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func joinSpecPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
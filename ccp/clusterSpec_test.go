@@ -0,0 +1,169 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseClusterSpec(t *testing.T) {
+
+	tests := []struct {
+		file  string
+		data  string
+		names []string
+	}{
+		{"one.yaml", "name: one\ndescription: first\n", []string{"one"}},
+		{"two.yaml", "name: one\n---\nname: two\n", []string{"one", "two"}},
+		{"list.yaml", "- name: one\n- name: two\n", []string{"one", "two"}},
+		{"one.json", `{"name": "one"}`, []string{"one"}},
+		{"two.json", "{\"name\": \"one\"}\n{\"name\": \"two\"}\n", []string{"one", "two"}},
+		{"list.json", `[{"name": "one"}, {"name": "two"}]`, []string{"one", "two"}},
+		{"spec", `{"name": "one"}`, []string{"one"}},
+	}
+
+	for _, test := range tests {
+		specs, err := ParseClusterSpec(test.file, []byte(test.data))
+		if err != nil {
+			t.Errorf("ParseClusterSpec(%s) error %v", test.file, err)
+			continue
+		}
+		var names []string
+		for _, spec := range specs {
+			names = append(names, *spec.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(test.names, ",") {
+			t.Errorf("ParseClusterSpec(%s) = %v, want %v", test.file, names, test.names)
+		}
+	}
+}
+
+func TestParseClusterSpecStrict(t *testing.T) {
+
+	tests := []struct {
+		file     string
+		data     string
+		document int
+		line     int
+		column   int
+		message  string
+	}{
+		{"spec.yaml", "name: one\ndescriptoin: first\n", 1, 2, 1, `did you mean "description"?`},
+		{"spec.yaml", "name: one\n---\nname: two\n  \ndescriptoin: second\n", 2, 5, 1, `did you mean "description"?`},
+		{"spec.json", "{\n  \"name\": \"one\",\n  \"descriptoin\": \"first\"\n}", 1, 3, 3, `did you mean "description"?`},
+		{"spec.json", "{\n  \"name\": \"one\",\n  \"description\" \"first\"\n}", 1, 3, 17, "invalid character"},
+		{"spec.json", "{\n  \"name\": \"one\",\n", 1, 3, 1, "unexpected end of file"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseClusterSpecStrict(test.file, []byte(test.data))
+		var problems SpecErrors
+		if !errors.As(err, &problems) || len(problems) != 1 {
+			t.Errorf("ParseClusterSpecStrict(%q) error %v, want one SpecError", test.data, err)
+			continue
+		}
+		problem := problems[0]
+		if problem.Document != test.document || problem.Line != test.line || problem.Column != test.column {
+			t.Errorf("ParseClusterSpecStrict(%q) at document %d %d:%d, want %d %d:%d", test.data,
+				problem.Document, problem.Line, problem.Column, test.document, test.line, test.column)
+		}
+		if !strings.Contains(problem.Message, test.message) {
+			t.Errorf("ParseClusterSpecStrict(%q) message %q, want %q", test.data, problem.Message, test.message)
+		}
+	}
+
+	// unknown fields are only problems when strict
+	_, err := ParseClusterSpec("spec.yaml", []byte("name: one\ndescriptoin: first\n"))
+	if err != nil {
+		t.Errorf("ParseClusterSpec with an unknown field error %v", err)
+	}
+}
+
+func TestJSONSpecReaderLineColumn(t *testing.T) {
+
+	data := []byte("{\n  \"a\": 1,\n\n  \"bb\": [1, 2],\n  \"c\": \"x\"\n}\n")
+
+	naive := func(offset int64) (int, int) {
+		line, column := 1, 1
+		for _, c := range data[:offset] {
+			if c == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+		}
+		return line, column
+	}
+
+	// forwards, repeated and backwards offsets all agree with counting from the start
+	offsets := []int64{0, 1, 2, 7, 7, 12, 30, 15, 3, int64(len(data)), int64(len(data)) + 5}
+
+	reader := jsonSpecReader{data: data}
+	for _, offset := range offsets {
+		line, column := reader.lineColumn(offset)
+		wantOffset := offset
+		if wantOffset > int64(len(data)) {
+			wantOffset = int64(len(data))
+		}
+		wantLine, wantColumn := naive(wantOffset)
+		if line != wantLine || column != wantColumn {
+			t.Errorf("lineColumn(%d) = %d:%d, want %d:%d", offset, line, column, wantLine, wantColumn)
+		}
+	}
+}
+
+func TestParseClusterSpecAliases(t *testing.T) {
+
+	var laughs strings.Builder
+	laughs.WriteString("a: &a [x, x, x, x, x, x, x, x, x, x]\n")
+	for i := 'b'; i <= 'i'; i++ {
+		laughs.WriteString(string(i) + ": &" + string(i) + " [")
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				laughs.WriteString(", ")
+			}
+			laughs.WriteString("*" + string(i-1))
+		}
+		laughs.WriteString("]\n")
+	}
+
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"name: &a [*a]\n", "spec.yaml:1:11: alias *a is inside its own anchor"},
+		{"name: one\nlabels: &a\n  team: *a\n", "spec.yaml:3:9: alias *a is inside its own anchor"},
+		{laughs.String(), "aliases expand to more than 10000 values"},
+		{"name: &a one\ndescription: *a\n", ""},
+	}
+
+	for _, test := range tests {
+		_, err := ParseClusterSpec("spec.yaml", []byte(test.data))
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("ParseClusterSpec(%q) error %v", test.data, err)
+			}
+			continue
+		}
+		var problems SpecErrors
+		if !errors.As(err, &problems) || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("ParseClusterSpec(%q) error %v, want %q", test.data, err, test.err)
+		}
+	}
+}
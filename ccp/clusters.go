@@ -17,16 +17,16 @@ package ccp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
-
-	validator "gopkg.in/validator.v2"
 )
 
 // Cluster v3 cluster
@@ -166,103 +166,71 @@ type VsphereClientConfig struct {
 	Password *string `json:"password,omitempty"`
 }
 
-// AddonsCatalogue List of all of the CCP Add-Ons
-// generated from https://mholt.github.io/json-to-go/
-// with future versions of CCP this may need to be re-generated with updated JSON catalogue from
-// path /v3/clusters/<clusteruuid>/catalog
-// this is working for CCP 6.x
-type AddonsCatalogue struct {
-	CcpMonitor struct {
-		DisplayName string `json:"displayName"`
-		Name        string `json:"name"`
-		Namespace   string `json:"namespace"`
-		Description string `json:"description"`
-		URL         string `json:"url"`
-	} `json:"_ccp-monitor"`
-	CcpEfk struct {
-		DisplayName string `json:"displayName"`
-		Name        string `json:"name"`
-		Namespace   string `json:"namespace"`
-		Description string `json:"description"`
-		URL         string `json:"url"`
-	} `json:"_ccp-efk"`
-	CcpKubernetesDashboard struct {
-		DisplayName   string   `json:"displayName"`
-		Name          string   `json:"name"`
-		Namespace     string   `json:"namespace"`
-		Description   string   `json:"description"`
-		URL           string   `json:"url"`
-		OverrideFiles []string `json:"overrideFiles"`
-	} `json:"_ccp-kubernetes-dashboard"`
-	CcpIstioOperator struct {
-		DisplayName  string   `json:"displayName"`
-		Name         string   `json:"name"`
-		Namespace    string   `json:"namespace"`
-		Description  string   `json:"description"`
-		URL          string   `json:"url"`
-		Conflicts    []string `json:"conflicts"`
-		Dependencies struct {
-			CcpIstio struct {
-				DisplayName string `json:"displayName"`
-				Name        string `json:"name"`
-				Namespace   string `json:"namespace"`
-				Description string `json:"description"`
-				URL         string `json:"url"`
-			} `json:"_ccp-istio"`
-		} `json:"dependencies"`
-	} `json:"_ccp-istio-operator"`
-	CcpHarborOperator struct {
-		DisplayName  string   `json:"displayName"`
-		Name         string   `json:"name"`
-		Namespace    string   `json:"namespace"`
-		Description  string   `json:"description"`
-		URL          string   `json:"url"`
-		Conflicts    []string `json:"conflicts"`
-		Dependencies struct {
-			CcpHarbor struct {
-				DisplayName string `json:"displayName"`
-				Name        string `json:"name"`
-				Namespace   string `json:"namespace"`
-				Description string `json:"description"`
-				URL         string `json:"url"`
-			} `json:"_ccp-harbor"`
-		} `json:"dependencies"`
-	} `json:"_ccp-harbor-operator"`
-	CcpKubeflow struct {
-		Name        string   `json:"name"`
-		Namespace   string   `json:"namespace"`
-		DisplayName string   `json:"displayName"`
-		Description string   `json:"description"`
-		URL         string   `json:"url"`
-		Conflicts   []string `json:"conflicts"`
-		Overrides   string   `json:"overrides"`
-	} `json:"_ccp-kubeflow"`
-	CcpHxcsi struct {
-		Name        string `json:"name"`
-		DisplayName string `json:"displayName"`
-		Description string `json:"description"`
-		URL         string `json:"url"`
-		Overrides   string `json:"overrides"`
-		Namespace   string `json:"namespace,omitempty"`
-	} `json:"_ccp-hxcsi"`
-}
-
-// ClusterInstalledAddons list of installed AddOn
+// AddonsCatalogue List of all of the CCP Add-Ons from path /v3/clusters/<clusteruuid>/catalog, keyed as
+// CCP returns them, e.g. _ccp-monitor. Addons added in newer CCP releases are kept
+type AddonsCatalogue map[string]CatalogEntry
+
+// CatalogEntry is one addon in the catalogue. The entries in Dependencies need this addon and are installed
+// after it, e.g. ccp-istio-cr after ccp-istio-operator
+type CatalogEntry struct {
+	DisplayName   string          `json:"displayName,omitempty"`
+	Name          string          `json:"name"`
+	Namespace     string          `json:"namespace,omitempty"`
+	Description   string          `json:"description,omitempty"`
+	URL           string          `json:"url"`
+	Overrides     string          `json:"overrides,omitempty"`
+	OverrideFiles []string        `json:"overrideFiles,omitempty"`
+	Conflicts     []string        `json:"conflicts,omitempty"`
+	Dependencies  AddonsCatalogue `json:"dependencies,omitempty"`
+}
+
+// Find returns the entry with a catalogue key or addon name, looking in the dependencies too
+func (c AddonsCatalogue) Find(name string) (*CatalogEntry, bool) {
+
+	for key, entry := range c {
+		if key == name || key == "_"+name || entry.Name == name {
+			return &entry, true
+		}
+		if found, ok := entry.Dependencies.Find(name); ok {
+			return found, true
+		}
+	}
+
+	return nil, false
+}
+
+// Keys returns the catalogue keys, sorted
+func (c AddonsCatalogue) Keys() []string {
+
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ClusterInstalledAddons list of installed AddOn. Next and Previous are the URLs of the other pages, nil on the
+// last and first page. GetClusterInstalledAddons returns every page in one list
 type ClusterInstalledAddons struct {
-	Count    int64      `json:"count"`
-	Next     int64      `json:"next"`
-	Previous int64      `json:"previous"`
-	Results  []struct { // results
-		Name        string   `json:"name"`
-		Namespace   string   `json:"namespace"`
-		DisplayName string   `json:"displayName"`
-		Description string   `json:"description"`
-		AddonStatus struct { // status
-			Name       string `json:"name"`
-			HelmStatus string `json:"helmStatus"`
-			Status     string `json:"status"`
-		} `json:"status"`
-	} `json:"results"`
+	Count    int64            `json:"count"`
+	Next     *string          `json:"next"`
+	Previous *string          `json:"previous"`
+	Results  []InstalledAddon `json:"results"`
+}
+
+// InstalledAddon is one addon in ClusterInstalledAddons
+type InstalledAddon struct {
+	Name        string   `json:"name"`
+	Namespace   string   `json:"namespace"`
+	DisplayName string   `json:"displayName"`
+	Description string   `json:"description"`
+	AddonStatus struct { // status
+		Name       string `json:"name"`
+		HelmStatus string `json:"helmStatus"`
+		Status     string `json:"status"`
+	} `json:"status"`
 }
 
 // Results - results for AddOns that have been installed or are installing
@@ -443,137 +411,69 @@ func (s *Client) ConvertJSONToCluster(jsonFile string) (*Cluster, error) {
 
 	var newCluster Cluster
 	err = json.Unmarshal([]byte(jsonBody), &newCluster)
+	if err != nil {
+		return nil, errors.New("Cannot read cluster JSON " + jsonFile + ": " + err.Error())
+	}
 
 	return &newCluster, nil
 }
 
-// AddClusterOld creates a new cluster without much error checking
-func (s *Client) AddClusterOld(cluster *Cluster) (*Cluster, error) {
-	Debug(1, "Entered AddCluster for "+string(*cluster.Name))
-
-	Debug(2, "Start validating Cluster struct")
-	errs := validator.Validate(cluster)
-	if errs != nil {
-		Debug(1, "Errors validating Cluster struct with validator.Validate(): "+string(errs.Error()))
-		return nil, errs
-	}
-	Debug(3, "No Errors validating Cluster struct")
-
-	url := s.BaseURL + "/v3/clusters/"
+// ConvertJSONToClusterStrict is ConvertJSONToCluster which rejects unknown fields and values of the wrong type,
+// reporting each with its line, column and JSON path, and suggesting the field that was probably meant
+func (s *Client) ConvertJSONToClusterStrict(jsonFile string) (*Cluster, error) {
+	Debug(1, "Entered ConvertJSONToClusterStrict")
 
-	j, err := json.Marshal(cluster)
+	jsonBody, err := ioutil.ReadFile(jsonFile)
 	if err != nil {
-		Debug(1, "Errors marshaling with json.Marshal(): "+string(err.Error()))
 		return nil, err
 	}
-	Debug(3, "No errors Marshaling JSON")
 
-	Debug(2, "About to POST to url "+url)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(j))
+	documents, err := parseJSONSpec(jsonFile, jsonBody)
 	if err != nil {
-		Debug(1, "Errors POSTing with http.NewRequest: "+string(err.Error()))
 		return nil, err
 	}
-
-	bytes, err := s.doRequest(req)
-	if err != nil {
-		Debug(1, "Errors POSTing with s.doRequest: "+string(err.Error()))
-		Debug(1, "POST response: "+string(bytes))
-		return nil, err
+	if len(documents) != 1 {
+		return nil, errors.New("Cluster JSON " + jsonFile + " must hold exactly one cluster")
 	}
-	Debug(3, "POST response: "+string(bytes))
-
-	var data Cluster
 
-	// err = json.Unmarshal(bytes, &data)
-	Debug(2, "Unmarshaling response")
-	err = json.Unmarshal(bytes, &data)
-	if err != nil {
-		Debug(1, "Errors unmarshaling with json.Unmarshal: "+string(err.Error()))
-		return nil, err
+	var newCluster Cluster
+	decoder := specDecoder{file: jsonFile, document: 1, strict: true}
+	if !decoder.decode(documents[0], &newCluster) {
+		return nil, decoder.problems
 	}
-	Debug(2, "Unmarshaled response successfully")
 
-	Debug(2, "CCP API responded with JSON payload for cluster named "+*data.Name+" with UUID "+*data.UUID)
-	if *data.UUID == "" {
-		Debug(1, "CCP API created cluster named "+*data.Name+" with UUID "+*data.UUID)
-	}
-	return &data, nil
+	return &newCluster, nil
 }
 
-// AddCluster creates a new cluster with error checking (Conor Murphy updates)
-func (s *Client) AddCluster(cluster *Cluster) (*Cluster, error) {
-
-	Debug(1, "Entered AddCluster for "+string(*cluster.Name))
-
-	Debug(2, "Start validating Cluster struct")
-	errs := validator.Validate(cluster)
-	if errs != nil {
-		Debug(1, "Errors validating Cluster struct with validator.Validate(): "+string(errs.Error()))
-		return nil, errs
-	}
-	Debug(3, "No Errors validating Cluster struct")
-
-	// https://stackoverflow.com/questions/44320960/omitempty-doesnt-omit-interface-nil-values-in-json
-	// *cluster.MasterNodePool.Nodes returns &[] and since this is not nil, omitempty, won't omit it when we marshal. Instead it includes nodes: null
-	// which CCP doesn't like. Therefore we need to check if it's empty and then set it to nil.
-
-	if cluster.MasterNodePool.Nodes != nil { // this check is for the CCP clientlibrary / ccpctl only
-		if len(*cluster.MasterNodePool.Nodes) == 0 { // this check is for the TF library creating a zero sized array
-			cluster.MasterNodePool.Nodes = nil
-		}
-	}
-	// Same as above but there can be multiple pools of worker nodes, therefore we need to iterate through, check if the nodes are empty,
-	// and if so set them to nil
-	var attr WorkerNodePool
-
-	if cluster.WorkerNodePool != nil { // check if this exists, if it does then process it
-		for i := 0; i < len(*cluster.WorkerNodePool); i++ {
-			attr = (*cluster.WorkerNodePool)[i]
-
-			if attr.Nodes != nil {
-				if len(*attr.Nodes) == 0 {
-					attr.Nodes = nil
-				}
-				(*cluster.WorkerNodePool)[i] = attr
-			}
-		}
-	}
+// AddClusterOld creates a new cluster. It is the same as AddCluster and kept for existing callers
+func (s *Client) AddClusterOld(cluster *Cluster) (*Cluster, error) {
+	return s.AddCluster(cluster)
+}
 
-	if cluster.NTPPools != nil { // check if this exists, if it does then process it
-		if len(*cluster.NTPPools) == 0 {
-			cluster.NTPPools = nil
-		}
-	}
+// AddCluster creates a new cluster. The cluster is validated and normalized with ClusterBuilderFrom(cluster).Build(),
+// no defaults are filled in
+func (s *Client) AddCluster(cluster *Cluster) (*Cluster, error) {
 
-	if cluster.NTPServers != nil {
-		if len(*cluster.NTPServers) == 0 {
-			cluster.NTPServers = nil
-		}
+	if cluster == nil || cluster.Name == nil {
+		return nil, errors.New("Cluster.Name is missing")
 	}
+	Debug(1, "Entered AddCluster for "+*cluster.Name)
 
-	if cluster.DockerNoProxy != nil {
-		if len(*cluster.DockerNoProxy) == 0 {
-			cluster.DockerNoProxy = nil
-		}
+	newCluster, err := ClusterBuilderFrom(cluster).Build()
+	if err != nil {
+		return nil, err
 	}
+	Debug(3, "No Errors validating Cluster struct")
 
-	if cluster.RegistriesRootCA != nil {
-		if len(*cluster.RegistriesRootCA) == 0 {
-			cluster.RegistriesRootCA = nil
-		}
-	}
+	return s.createCluster(newCluster)
+}
 
-	if cluster.RegistriesInsecure != nil {
-		if len(*cluster.RegistriesInsecure) == 0 {
-			cluster.RegistriesInsecure = nil
-		}
-	}
+// createCluster POSTs a built cluster
+func (s *Client) createCluster(cluster *Cluster) (*Cluster, error) {
 
 	url := s.BaseURL + "/v3/clusters/"
 
-	j, err := json.Marshal(&cluster)
-
+	j, err := json.Marshal(cluster)
 	if err != nil {
 		Debug(1, "Errors marshaling with json.Marshal(): "+string(err.Error()))
 		return nil, err
@@ -597,7 +497,6 @@ func (s *Client) AddCluster(cluster *Cluster) (*Cluster, error) {
 
 	var data Cluster
 
-	// err = json.Unmarshal(bytes, &data)
 	Debug(2, "Unmarshaling response")
 	err = json.Unmarshal(bytes, &data)
 	if err != nil {
@@ -606,140 +505,48 @@ func (s *Client) AddCluster(cluster *Cluster) (*Cluster, error) {
 	}
 	Debug(2, "Unmarshaled response successfully")
 
-	Debug(2, "CCP API responded with JSON payload for cluster named "+*data.Name+" with UUID "+*data.UUID)
-	if *data.UUID == "" {
-		Debug(1, "CCP API created cluster named "+*data.Name+" with UUID "+*data.UUID)
+	if data.UUID == nil || *data.UUID == "" {
+		return nil, errors.New("CCP API did not return a UUID for cluster " + *cluster.Name)
 	}
+	Debug(2, "CCP API responded with JSON payload for cluster named "+*cluster.Name+" with UUID "+*data.UUID)
 
 	return &data, nil
 }
 
-// AddClusterSynchronous creates a new cluster but waits until the cluster is created before returning
+// AddClusterSynchronous creates a new cluster with AddCluster and waits until the cluster is ready before returning
 func (s *Client) AddClusterSynchronous(cluster *Cluster) (*Cluster, error) {
 
-	errs := validator.Validate(cluster)
-	if errs != nil {
-		return nil, errs
-	}
-
-	// https://stackoverflow.com/questions/44320960/omitempty-doesnt-omit-interface-nil-values-in-json
-	// *cluster.MasterNodePool.Nodes returns &[] and since this is not nil, omitempty, won't omit it when we marshal. Instead it includes nodes: null
-	// which CCP doesn't like. Therefore we need to check if it's empty and then set it to nil.
-
-	if cluster.MasterNodePool.Nodes != nil { // this check is for the CCP clientlibrary / ccpctl only
-		if len(*cluster.MasterNodePool.Nodes) == 0 { // this check is for the TF library creating a zero sized array
-			cluster.MasterNodePool.Nodes = nil
-		}
-	}
-	// Same as above but there can be multiple pools of worker nodes, therefore we need to iterate through, check if the nodes are empty,
-	// and if so set them to nil
-	var attr WorkerNodePool
-
-	if cluster.WorkerNodePool != nil { // check if this exists, if it does then process it
-		for i := 0; i < len(*cluster.WorkerNodePool); i++ {
-			attr = (*cluster.WorkerNodePool)[i]
-
-			if attr.Nodes != nil {
-				if len(*attr.Nodes) == 0 {
-					attr.Nodes = nil
-				}
-				(*cluster.WorkerNodePool)[i] = attr
-			}
-		}
-	}
-
-	if cluster.NTPPools != nil { // check if this exists, if it does then process it
-		if len(*cluster.NTPPools) == 0 {
-			cluster.NTPPools = nil
-		}
-	}
-
-	if cluster.NTPServers != nil {
-		if len(*cluster.NTPServers) == 0 {
-			cluster.NTPServers = nil
-		}
-	}
-
-	if cluster.DockerNoProxy != nil {
-		if len(*cluster.DockerNoProxy) == 0 {
-			cluster.DockerNoProxy = nil
-		}
-	}
-
-	if cluster.RegistriesRootCA != nil {
-		if len(*cluster.RegistriesRootCA) == 0 {
-			cluster.RegistriesRootCA = nil
-		}
-	}
-
-	if cluster.RegistriesInsecure != nil {
-		if len(*cluster.RegistriesInsecure) == 0 {
-			cluster.RegistriesInsecure = nil
-		}
-	}
-
-	// Need this because even though we don't have the networks setting configured for contiv-aci, if we leave it
-	// out then CCP will complain it's empty. We also can't marshall an empty slice as it converts to null which doesn't work
-	// see here for details https://apoorvam.github.io/blog/2017/golang-json-marshal-slice-as-empty-array-not-null
-	// Also, it seems CCP complains when load_balancer_ip_num is missing even though it's not used for the aci cni
-
-	if cluster.NetworkPlugin.Name != nil {
-		if *cluster.NetworkPlugin.Name == "contiv-aci" {
-			*cluster.Infra.Networks = make([]string, 0)
-			*cluster.LoadBalancerIPNum = 1
-		}
-	}
-	url := s.BaseURL + "/v3/clusters/"
-
-	j, err := json.Marshal(&cluster)
-
-	if err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(j))
-	if err != nil {
-		return nil, err
-	}
-
-	bytes, err := s.doRequest(req)
+	created, err := s.AddCluster(cluster)
 	if err != nil {
 		return nil, err
 	}
-	var data Cluster
 
-	// err = json.Unmarshal(bytes, &data)
-	err = json.Unmarshal(bytes, &data)
-	if err != nil {
-		return nil, err
-	}
+	return s.WaitForClusterReady(context.Background(), *created.UUID)
+}
 
-	time.Sleep(5 * time.Second)
+// DeleteCluster deletes a cluster. Protected clusters are refused, use DeleteProtectedCluster to override
+func (s *Client) DeleteCluster(clusterUUID string) error {
+	Debug(1, "Entered DeleteCluster for UUID "+clusterUUID)
 
-	status, err := s.GetClusterStatusByName(*cluster.Name)
+	if clusterUUID == "" {
+		return errors.New("Cluster UUID to delete is required")
+	}
 
+	cluster, err := s.GetClusterByUUID(clusterUUID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	for *status == "CREATING" {
-
-		status, err = s.GetClusterStatusByName(*cluster.Name)
-
-		if err != nil {
-			return nil, err
-		}
-
-		time.Sleep(5 * time.Second)
-
+	if s.IsClusterProtected(cluster) {
+		Debug(1, "Refusing to delete protected cluster "+clusterUUID)
+		return fmt.Errorf("%w: %s", ErrClusterProtected, clusterUUID)
 	}
 
-	return &data, nil
-
+	return s.deleteCluster(clusterUUID)
 }
 
-// DeleteCluster deletes a cluster
-func (s *Client) DeleteCluster(clusterUUID string) error {
-	Debug(1, "Entered DeleteCluster for UUID "+clusterUUID)
+// deleteCluster sends the DELETE for a cluster without any protection checks
+func (s *Client) deleteCluster(clusterUUID string) error {
 
 	if clusterUUID == "" {
 		return errors.New("Cluster UUID to delete is required")
@@ -787,54 +594,22 @@ func GetKubeVerFromImage(value string) string {
 	return value[posFirstAdjusted:posLast]
 }
 
-// AddClusterBasic add a v3 cluster the easy way
+// AddClusterBasic adds a v3 cluster the easy way. Only the name, datacenter, master template and master SSH user
+// and key are required. The vSphere infrastructure provider is looked up and everything else which is not set
+// gets the ClusterBuilder defaults. Worker pools given are kept, with their unset values filled in
 func (s *Client) AddClusterBasic(cluster *Cluster) (*Cluster, error) {
-	Debug(1, "Entered AddClusterBasic for cluster "+string(*cluster.Name))
-	/*
-
-		This function was added in order to provide users a better experience with adding clusters. The list of required
-		fields has been shortend with all defaults and computed values such as UUIDs to be automatically configured on behalf of the user.
-
-		The following fields and values will be configured. The remainder to be specified by the user
-
-		ProviderClientConfigUUID
-		KubernetesVersion - default will be set to 1.10.1
-		Type - default will be set to 1
-		Deployer
-			ProviderType will be set to "vsphere"
-			Provider
-				VsphereDataCenter - already specified as part of Cluster struct so will use this same value
-				VsphereClientConfigUUID
-				VsphereDatastore - already specified as part of Cluster struct so will use this same value
-				VsphereWorkingDir - default will be set to /VsphereDataCenter/vm
-		NetworkPlugin
-			Name - default will be set to contiv-vpp
-			Status - default will be set to ""
-			Details - default will be set to "{\"pod_cidr\":\"192.168.0.0/16\"}"
-		WorkerNodePool
-			VCPUs - default will be set to 2
-			Memory - default will be set to 16384
-		MasterNodePool
-			VCPUs - default will be set to 2
-			Memory - default will be set to 8192
-
-	*/
-
-	var data Cluster
 
-	// The following will configured the defaults for the cluster as specified above as well as check that the minimum
-	// fields are provided
-
-	if nonzero(cluster.Name) {
+	if cluster == nil || nonzero(cluster.Name) {
 		return nil, errors.New("Cluster.Name is missing")
 	}
-	if nonzero(cluster.Infra.Datacenter) {
+	Debug(1, "Entered AddClusterBasic for cluster "+*cluster.Name)
+
+	if cluster.Infra == nil || nonzero(cluster.Infra.Datacenter) {
 		return nil, errors.New("Cluster.Infra.Datacenter is missing")
 	}
-
-	// if nonzero(cluster.ResourcePool) {
-	// 	return nil, errors.New("Cluster.ResourcePool is missing")
-	// }
+	if cluster.MasterNodePool == nil || nonzero(cluster.MasterNodePool.Template) {
+		return nil, errors.New("cluster.MasterNodePool.Template is missing")
+	}
 	if nonzero(cluster.MasterNodePool.SSHUser) {
 		return nil, errors.New("cluster.MasterNodePool.SSHUser is missing")
 	}
@@ -842,672 +617,162 @@ func (s *Client) AddClusterBasic(cluster *Cluster) (*Cluster, error) {
 		return nil, errors.New("cluster.MasterNodePool.SSHKey is missing")
 	}
 
-	// loop over array of WorkerNodePool
-	for _, v := range *cluster.WorkerNodePool {
-
-		if nonzero(v.SSHUser) {
-			return nil, errors.New("v.SSHUser is missing")
-		}
+	builder := ClusterBuilderFrom(cluster).Defaults(true)
 
-		if nonzero(v.SSHKey) {
-			return nil, errors.New("v.SSHKey is missing")
-		}
-		if nonzero(v.Size) {
-			return nil, errors.New("v.Size is missing")
-		}
-		if nonzero(v.Template) {
-			return nil, errors.New("v.Template is missing")
+	if nonzero(cluster.InfraProviderUUID) {
+		// Retrieve the provider client config UUID rather than have the user need to provide this themselves.
+		// This is also built for a single provider client config and as of CCP 1.5 this wll be Vsphere
+		providerClientConfigs, err := s.GetInfraProviderByName("vsphere")
+		if err != nil {
+			return nil, err
 		}
+		builder.Provider(*providerClientConfigs.UUID)
 	}
 
-	if nonzero(cluster.MasterNodePool.Size) {
-		return nil, errors.New("cluster.MasterNodePool.Size is missing")
-	}
-
-	if nonzero(cluster.MasterNodePool.Template) {
-		return nil, errors.New("cluster.MasterNodePool.Template is missing")
-	}
-
-	// check that cluster.MasterNodePool.Template and cluster.WorkerNodePool.Template are the same
-
-	// Retrieve the provider client config UUID rather than have the user need to provide this themselves.
-	// This is also built for a single provider client config and as of CCP 1.5 this wll be Vsphere
-	providerClientConfigs, err := s.GetInfraProviderByName("vsphere")
+	newCluster, err := builder.Build()
 	if err != nil {
 		return nil, err
 	}
 
-	networkPlugin := NetworkPlugin{
-		Name: String("calico"),
-		// Details: String("{\"pod_cidr\":\"192.168.0.0/16\"}"),
-		Details: &NetworkPluginDetails{
-			PodCIDR: String("192.168.0.0/16"),
-		},
-	}
+	return s.createCluster(newCluster)
+}
 
-	workerNodePool := WorkerNodePool{
-		Size:     Int64(1),
-		VCPUs:    Int64(2),
-		Memory:   Int64(32768),
-		Template: String(*cluster.MasterNodePool.Template), // use same template as master
-	}
+// InstallAddonIstioOp installs the Istio Operator and Istio with InstallAddon
+func (s *Client) InstallAddonIstioOp(clusterUUID string) error {
+	return s.InstallAddon(clusterUUID, "ccp-istio-operator")
+}
 
-	masterNodePool := MasterNodePool{
-		Size:     Int64(1),
-		VCPUs:    Int64(2),
-		Memory:   Int64(16384),
-		Template: String(*cluster.MasterNodePool.Template),
-	}
+// InstallAddonIstioInstance installs Istio with InstallAddon, after the Istio Operator it needs
+func (s *Client) InstallAddonIstioInstance(clusterUUID string) error {
+	return s.InstallAddon(clusterUUID, "ccp-istio-cr")
+}
 
-	// Since it returns a list we will use the UUID from the first element
-	cluster.InfraProviderUUID = String(*providerClientConfigs.UUID)
-	// cluster.KubernetesVersion = String("1.16.3") // todo: fetch this somehow
-	// below should work, but issues with *string / string types
-	// kubever := GetKubeVerFromImage(*cluster.MasterNodePool.Template)
-	// cluster.KubernetesVersion = &kubever
-	cluster.KubernetesVersion = String(GetKubeVerFromImage(*cluster.MasterNodePool.Template))
+// InstallAddonIstio installs the Istio Operator and Istio
+func (s *Client) InstallAddonIstio(clusterUUID string) error {
+	return s.InstallAddonIstioInstance(clusterUUID)
+}
 
-	// cluster.Type = Int64(1)
-	cluster.NetworkPlugin = &networkPlugin
-	// cluster.Deployer = &deployer
+// InstallAddonDashboard installs the Kubernetes dashboard with InstallAddon
+func (s *Client) InstallAddonDashboard(clusterUUID string) error {
+	return s.InstallAddon(clusterUUID, "kubernetes-dashboard")
+}
 
-	//	cluster.WorkerNodePool = &workerNodePool
-	cluster.WorkerNodePool = &[]WorkerNodePool{workerNodePool}
+// InstallAddonMonitoring installs monitoring with InstallAddon
+func (s *Client) InstallAddonMonitoring(clusterUUID string) error {
+	return s.InstallAddon(clusterUUID, "ccp-monitor")
+}
 
-	cluster.MasterNodePool = &masterNodePool
+// InstallAddonLogging installs logging with InstallAddon
+func (s *Client) InstallAddonLogging(clusterUUID string) error {
+	return s.InstallAddon(clusterUUID, "ccp-efk")
+}
 
-	url := s.BaseURL + "/v3/clusters"
+// InstallAddonHarborOp installs the Harbor Operator with InstallAddon
+func (s *Client) InstallAddonHarborOp(clusterUUID string) error {
+	return s.InstallAddon(clusterUUID, "ccp-harbor-operator")
+}
 
-	j, err := json.Marshal(cluster)
+// InstallAddonHarborInstance installs the Harbor registry with InstallAddon, after the Harbor Operator it needs
+func (s *Client) InstallAddonHarborInstance(clusterUUID string) error {
+	return s.InstallAddon(clusterUUID, "ccp-harbor-cr")
+}
 
+// InstallAddonHarbor installs the Harbor Operator and the Harbor registry
+func (s *Client) InstallAddonHarbor(clusterUUID string) error {
+	err := s.InstallAddonHarborOp(clusterUUID)
 	if err != nil {
-
-		return nil, err
+		Debug(1, "Failed to add Add-On Harbor Operator: "+string(err.Error()))
+		return err
 	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(j))
+	err = s.InstallAddonHarborInstance(clusterUUID)
 	if err != nil {
-		return nil, err
+		Debug(1, "Failed to add Add-On Harbor Instance: "+string(err.Error()))
+		return err
 	}
+	return nil
+}
 
-	bytes, err := s.doRequest(req)
+// InstallAddon installs addon and waits up to AddonInstallTimeout for each addon to finish. The addon is looked
+// up in the cluster catalogue with GetAddonRegistry, the addons it needs and its dependencies are installed with it
+func (s *Client) InstallAddon(clusterUUID string, addonName string) error {
 
-	if err != nil {
-		return nil, err
+	if clusterUUID == "" {
+		return errors.New("Cluster UUID is required")
 	}
 
-	err = json.Unmarshal(bytes, &data)
-
+	registry, err := s.GetAddonRegistry(clusterUUID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	cluster = &data
+	return s.InstallAddonFromRegistry(context.Background(), clusterUUID, registry, addonName, nil)
+}
 
-	return cluster, nil
+// InstallAddonAndWaitUntilInstalled install addon and wait until it's completed, up to AddonInstallTimeout for
+// the one addon in jsonBody.
+// The error is an *AddonError if the addon fails or does not finish in time
+func (s *Client) InstallAddonAndWaitUntilInstalled(clusterUUID string, addonName string, jsonBody []byte) error {
+
+	return s.installAddonBody(context.Background(), clusterUUID, addonName, jsonBody, AddonInstallTimeout)
 }
 
-// InstallAddonIstioOp Installs the Istio Operator
-func (s *Client) InstallAddonIstioOp(clusterUUID string) error {
-	Debug(1, "Entered InstallAddonIstio for UUID "+clusterUUID)
+// DeleteAddonLogging deletes the addon
+func (s *Client) DeleteAddonLogging(clusterUUID string) error {
+	Debug(1, "Entered DeleteAddonLogging for UUID "+clusterUUID)
 
 	if clusterUUID == "" {
-		return errors.New("Cluster UUID is required")
+		return errors.New("Cluster UUID to delete is required")
 	}
 
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
-
-	jsonBody := []byte(`
-	{
-        "displayName": "Istio Operator",
-        "name": "ccp-istio-operator",
-        "namespace": "ccp",
-        "description": "Istio Operator",
-        "url": "/opt/ccp/charts/ccp-istio-operator.tgz",
-        "conflicts": [
-            "ccp-kubeflow",
-            "ccp-harbor-operator"
-        ],
-        "dependencies": {
-            "_ccp-istio": {
-                "displayName": "Istio",
-                "name": "ccp-istio-cr",
-                "namespace": "ccp",
-                "description": "Istio (REQUIRES ISTIO OPERATOR)",
-                "url": "/opt/ccp/charts/ccp-istio-cr.tgz"
-            }
-		}
-	}`)
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/ccp-efk/"
+	Debug(2, "Sending HTTP delte to "+url)
+	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		return err
 	}
-
-	resp, err := s.doRequest(req)
+	_, err = s.doRequest(req)
 	if err != nil {
 		return err
 	}
-	Debug(3, "Response is:")
-	Debug(3, string(resp))
 
 	Debug(2, "Request sent to API with success response")
 	return nil
 }
 
-// InstallAddonIstioInstance Installs the Istio Instance (install the Operator first)
-func (s *Client) InstallAddonIstioInstance(clusterUUID string) error {
-	Debug(1, "Entered InstallAddonIstioInstance for UUID "+clusterUUID)
+// DeleteAddonMonitor deletes the addon
+func (s *Client) DeleteAddonMonitor(clusterUUID string) error {
+	Debug(1, "Entered DeleteAddonMonitor for UUID "+clusterUUID)
 
 	if clusterUUID == "" {
-		return errors.New("Cluster UUID is required")
+		return errors.New("Cluster UUID to delete is required")
 	}
 
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
-
-	jsonBody := []byte(`
-	{
-		"displayName": "Istio",
-		"name": "ccp-istio-cr",
-		"namespace": "ccp",
-		"description": "Istio (REQUIRES ISTIO OPERATOR)",
-		"url": "/opt/ccp/charts/ccp-istio-cr.tgz"
-	}`)
+	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/ccp-monitor/"
+	Debug(2, "Sending HTTP delte to "+url)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		return err
 	}
-
-	resp, err := s.doRequest(req)
+	_, err = s.doRequest(req)
 	if err != nil {
 		return err
 	}
-	Debug(3, "Response is:")
-	Debug(3, string(resp))
 
 	Debug(2, "Request sent to API with success response")
 	return nil
 }
 
-// InstallAddonIstio install both
-func (s *Client) InstallAddonIstio(clusterUUID string) error {
-	err := s.InstallAddonIstioOp(clusterUUID)
-	if err != nil {
-		Debug(1, "Failed to add Add-On Istio Operator: "+string(err.Error()))
-		return err
+// DeleteAddonIstioInstance deletes the addon
+func (s *Client) DeleteAddonIstioInstance(clusterUUID string) error {
+	Debug(1, "Entered DeleteAddonIstioInstance for UUID "+clusterUUID)
+
+	if clusterUUID == "" {
+		return errors.New("Cluster UUID to delete is required")
 	}
-	time.Sleep(2 * time.Second) // wait 2 seconds before sending the next request
-	err = s.InstallAddonIstioInstance(clusterUUID)
-	if err != nil {
-		Debug(1, "Failed to add Add-On Istio Instance: "+string(err.Error()))
-		return err
-	}
-	return nil
-}
-
-// InstallAddonDashboard Installs the Istio Instance (install the Operator first)
-func (s *Client) InstallAddonDashboard(clusterUUID string) error {
-	Debug(1, "Entered InstallAddonDashboard for UUID "+clusterUUID)
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID is required")
-	}
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
-
-	jsonBody := []byte(`
-	{
-		"displayName": "Dashboard",
-		"name": "kubernetes-dashboard",
-		"namespace": "ccp",
-		"description": "Dashboard",
-		"url": "/opt/ccp/charts/kubernetes-dashboard.tgz",
-		"overrideFiles": [
-			"/opt/ccp/charts/kubernetes-dashboard.yaml"
-		]
-	}`)
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return err
-	}
-
-	resp, err := s.doRequest(req)
-	if err != nil {
-		return err
-	}
-	Debug(3, "Response is:")
-	Debug(3, string(resp))
-
-	Debug(2, "Request sent to API with success response")
-	return nil
-}
-
-// InstallAddonMonitoring Installs the Istio Instance (install the Operator first)
-func (s *Client) InstallAddonMonitoring(clusterUUID string) error {
-	Debug(1, "Entered InstallAddonMonitoring for UUID "+clusterUUID)
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID is required")
-	}
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
-
-	jsonBody := []byte(`
-	{
-		"displayName": "Monitoring",
-		"name": "ccp-monitor",
-		"namespace": "ccp",
-		"description": "Monitoring",
-		"url": "/opt/ccp/charts/ccp-monitor.tgz"
-	}`)
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return err
-	}
-
-	resp, err := s.doRequest(req)
-	if err != nil {
-		return err
-	}
-	Debug(3, "Response is:")
-	Debug(3, string(resp))
-
-	Debug(2, "Request sent to API with success response")
-	return nil
-}
-
-// InstallAddonLogging Installs the Istio Instance (install the Operator first)
-func (s *Client) InstallAddonLogging(clusterUUID string) error {
-	Debug(1, "Entered InstallAddonLogging for UUID "+clusterUUID)
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID is required")
-	}
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
-
-	jsonBody := []byte(`
-	{
-		"displayName": "Logging",
-		"name": "ccp-efk",
-		"namespace": "ccp",
-		"description": "Logging",
-		"url": "/opt/ccp/charts/ccp-efk.tgz"
-	}`)
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return err
-	}
-
-	resp, err := s.doRequest(req)
-	if err != nil {
-		return err
-	}
-	Debug(3, "Response is:")
-	Debug(3, string(resp))
-
-	Debug(2, "Request sent to API with success response")
-	return nil
-}
-
-// InstallAddonHarborOp Installs the Istio Instance (install the Operator first)
-func (s *Client) InstallAddonHarborOp(clusterUUID string) error {
-	Debug(1, "Entered InstallAddonHarborOp for UUID "+clusterUUID)
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID is required")
-	}
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
-
-	jsonBody := []byte(`
-	{
-        "displayName": "Harbor Operator",
-        "name": "ccp-harbor-operator",
-        "namespace": "ccp",
-        "description": "Harbor Operator",
-        "url": "/opt/ccp/charts/ccp-harbor-operator.tgz",
-        "conflicts": [
-            "ccp-istio-operator"
-		]
-	}`)
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return err
-	}
-
-	resp, err := s.doRequest(req)
-	if err != nil {
-		return err
-	}
-	Debug(3, "Response is:")
-	Debug(3, string(resp))
-
-	Debug(2, "Request sent to API with success response")
-	return nil
-}
-
-// InstallAddonHarborInstance Installs the Istio Instance (install the Operator first)
-func (s *Client) InstallAddonHarborInstance(clusterUUID string) error {
-	Debug(1, "Entered InstallAddonHarborInstance for UUID "+clusterUUID)
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID is required")
-	}
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
-
-	jsonBody := []byte(`
-	{
-		"displayName": "Harbor",
-		"name": "ccp-harbor-cr",
-		"namespace": "ccp",
-		"description": "Harbor registry",
-		"url": "/opt/ccp/charts/ccp-harbor-cr.tgz"
-	}`)
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return err
-	}
-
-	resp, err := s.doRequest(req)
-	if err != nil {
-		return err
-	}
-	Debug(3, "Response is:")
-	Debug(3, string(resp))
-
-	Debug(2, "Request sent to API with success response")
-	return nil
-}
-
-// InstallAddonHarbor install both
-func (s *Client) InstallAddonHarbor(clusterUUID string) error {
-	err := s.InstallAddonHarborOp(clusterUUID)
-	if err != nil {
-		Debug(1, "Failed to add Add-On Istio Operator: "+string(err.Error()))
-		return err
-	}
-	time.Sleep(2 * time.Second) // wait 2 seconds before sending the next request
-	err = s.InstallAddonHarborInstance(clusterUUID)
-	if err != nil {
-		Debug(1, "Failed to add Add-On Istio Instance: "+string(err.Error()))
-		return err
-	}
-	return nil
-}
-
-// InstallAddon installs addon and waits for it to finish
-func (s *Client) InstallAddon(clusterUUID string, addonName string) error {
-
-	var jsonBody []byte
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID is required")
-	}
-
-	switch addonName {
-
-	case "kubernetes-dashboard":
-		jsonBody = []byte(`
-		{
-			"displayName": "Dashboard",
-			"name": "kubernetes-dashboard",
-			"namespace": "ccp",
-			"description": "Dashboard",
-			"url": "/opt/ccp/charts/kubernetes-dashboard.tgz",
-			"overrideFiles": [
-				"/opt/ccp/charts/kubernetes-dashboard.yaml"
-			]
-		}`)
-
-		err = s.InstallAddonAndWaitUntilInstalled(clusterUUID, "kubernetes-dashboard", jsonBody)
-
-		if err != nil {
-			return err
-		}
-
-	case "ccp-efk", "logging", "efk":
-		jsonBody = []byte(`
-		{
-			"displayName": "Logging",
-			"name": "ccp-efk",
-			"namespace": "ccp",
-			"description": "Logging",
-			"url": "/opt/ccp/charts/ccp-efk.tgz"
-		}`)
-
-		err = s.InstallAddonAndWaitUntilInstalled(clusterUUID, "ccp-efk", jsonBody)
-
-		if err != nil {
-			return err
-		}
-
-	case "ccp-monitor", "monitor", "monitoring":
-		jsonBody = []byte(`
-		{
-			"displayName": "Monitoring",
-			"name": "ccp-monitor",
-			"namespace": "ccp",
-			"description": "Monitoring",
-			"url": "/opt/ccp/charts/ccp-monitor.tgz"
-		}`)
-
-		err = s.InstallAddonAndWaitUntilInstalled(clusterUUID, "ccp-monitor", jsonBody)
-
-		if err != nil {
-			return err
-		}
-
-	case "istio":
-		jsonBody = []byte(`
-		{
-			"displayName": "Istio Operator",
-			"name": "ccp-istio-operator",
-			"namespace": "ccp",
-			"description": "Istio Operator",
-			"url": "/opt/ccp/charts/ccp-istio-operator.tgz",
-			"conflicts": [
-				"ccp-kubeflow",
-				"ccp-harbor-operator"
-			],
-			"dependencies": {
-				"_ccp-istio": {
-					"displayName": "Istio",
-					"name": "ccp-istio-cr",
-					"namespace": "ccp",
-					"description": "Istio (REQUIRES ISTIO OPERATOR)",
-					"url": "/opt/ccp/charts/ccp-istio-cr.tgz"
-				}
-			}
-		}`)
-
-		err = s.InstallAddonAndWaitUntilInstalled(clusterUUID, "ccp-istio-operator", jsonBody)
-
-		if err != nil {
-			return err
-		}
-
-		err = s.InstallAddonIstioInstance(clusterUUID)
-		if err != nil {
-			return err
-		}
-
-	case "harbor":
-		jsonBody = []byte(`
-		{
-			"displayName": "Harbor Operator",
-			"name": "ccp-harbor-operator",
-			"namespace": "ccp",
-			"description": "Harbor Operator",
-			"url": "/opt/ccp/charts/ccp-harbor-operator.tgz",
-			"conflicts": [
-				"ccp-istio-operator"
-			]
-		}`)
-
-		err = s.InstallAddonAndWaitUntilInstalled(clusterUUID, "ccp-harbor-operator", jsonBody)
-
-		if err != nil {
-			return err
-		}
-
-		err = s.InstallAddonHarborInstance(clusterUUID)
-		if err != nil {
-			return err
-		}
-
-	case "ccp-kubeflow", "kubeflow":
-
-		jsonBody, err = s.GetKubeflowAddonConfig(clusterUUID)
-
-		if err != nil {
-			return err
-		}
-
-		err = s.InstallAddonAndWaitUntilInstalled(clusterUUID, "ccp-kubeflow", jsonBody)
-
-		if err != nil {
-			return err
-		}
-
-	case "hxcsi", "hx-csi":
-		err = s.InstallAddonHXCSI(clusterUUID)
-		if err != nil {
-			return err
-		}
-
-		addonInstalled, err := s.IsAddonInstalled(clusterUUID, "ccp-hxcsi")
-
-		for !*addonInstalled {
-			addonInstalled, err = s.IsAddonInstalled(clusterUUID, "ccp-hxcsi")
-
-			if err != nil {
-				return err
-			}
-
-			time.Sleep(2 * time.Second)
-		}
-
-	default:
-		return errors.New("Incorrect addon name provided for Add: '" + addonName + "'. Options are 'kubernetes-dashboard', 'ccp-efk', 'ccp-monitor', 'ccp-istio','harbor', 'ccp-kubeflow', 'hx-csi'")
-
-	}
-
-	return nil
-}
-
-// InstallAddonAndWaitUntilInstalled install addon and wait until it's completed
-func (s *Client) InstallAddonAndWaitUntilInstalled(clusterUUID string, addonName string, jsonBody []byte) error {
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
-
-	addonInstalled, err := s.IsAddonInstalled(clusterUUID, addonName)
-
-	if err != nil {
-		return err
-	}
-
-	if !*addonInstalled {
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-		if err != nil {
-			return err
-		}
-
-		_, err = s.doRequest(req)
-		if err != nil {
-			return err
-		}
-
-	}
-
-	addonInstalled, err = s.IsAddonInstalled(clusterUUID, addonName)
-
-	retries := 150 // 150 retries before failing = 300 seconds = 5 minutes
-	count := 0
-	for !*addonInstalled {
-		// retry X many times
-		count = count + 1
-		if count >= retries {
-			return errors.New("ccp addon " + addonName + " failed to install after all retries")
-		}
-
-		addonInstalled, err = s.IsAddonInstalled(clusterUUID, addonName)
-
-		if err != nil {
-			return err
-		}
-
-		time.Sleep(2 * time.Second)
-
-	}
-
-	return nil
-}
-
-// DeleteAddonLogging deletes the addon
-func (s *Client) DeleteAddonLogging(clusterUUID string) error {
-	Debug(1, "Entered DeleteAddonLogging for UUID "+clusterUUID)
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID to delete is required")
-	}
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/ccp-efk/"
-	Debug(2, "Sending HTTP delte to "+url)
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return err
-	}
-	_, err = s.doRequest(req)
-	if err != nil {
-		return err
-	}
-
-	Debug(2, "Request sent to API with success response")
-	return nil
-}
-
-// DeleteAddonMonitor deletes the addon
-func (s *Client) DeleteAddonMonitor(clusterUUID string) error {
-	Debug(1, "Entered DeleteAddonMonitor for UUID "+clusterUUID)
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID to delete is required")
-	}
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/ccp-monitor/"
-	Debug(2, "Sending HTTP delte to "+url)
-
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return err
-	}
-	_, err = s.doRequest(req)
-	if err != nil {
-		return err
-	}
-
-	Debug(2, "Request sent to API with success response")
-	return nil
-}
-
-// DeleteAddonIstioInstance deletes the addon
-func (s *Client) DeleteAddonIstioInstance(clusterUUID string) error {
-	Debug(1, "Entered DeleteAddonIstioInstance for UUID "+clusterUUID)
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID to delete is required")
-	}
-
-	addonInstalled, err := s.IsAddonInstalled(clusterUUID, "ccp-istio-operator")
-
+
+	addonInstalled, err := s.IsAddonInstalled(clusterUUID, "ccp-istio-operator")
+
 	if err != nil {
 		return err
 	}
@@ -1670,7 +935,7 @@ func (s *Client) DeleteAddonHarbor(clusterUUID string) error {
 }
 
 // GetAddonsCatalogue returns a list of Addons
-func (s *Client) GetAddonsCatalogue(clusterUUID string) (*AddonsCatalogue, error) {
+func (s *Client) GetAddonsCatalogue(clusterUUID string) (AddonsCatalogue, error) {
 	// https://mholt.github.io/json-to-go/
 	Debug(3, "GetAddonsCatalogue for cluster "+clusterUUID)
 
@@ -1685,7 +950,7 @@ func (s *Client) GetAddonsCatalogue(clusterUUID string) (*AddonsCatalogue, error
 		return nil, err
 	}
 	Debug(3, string(bytes))
-	var data *AddonsCatalogue
+	var data AddonsCatalogue
 
 	err = json.Unmarshal(bytes, &data)
 	if err != nil {
@@ -1695,27 +960,21 @@ func (s *Client) GetAddonsCatalogue(clusterUUID string) (*AddonsCatalogue, error
 	return data, nil
 }
 
-// GetClusterInstalledAddons returns a list of Addons
+// GetClusterInstalledAddons returns a list of Addons, reading every page. Use InstalledAddons to walk the
+// pages one at a time
 func (s *Client) GetClusterInstalledAddons(clusterUUID string) (*ClusterInstalledAddons, error) {
 	Debug(3, "GetClusterInstalledAddons for cluster "+clusterUUID)
 
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
+	data := &ClusterInstalledAddons{Results: []InstalledAddon{}}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+	addons := s.InstalledAddons(clusterUUID)
+	for addons.Next() {
+		data.Results = append(data.Results, *addons.Addon())
 	}
-	bytes, err := s.doRequest(req)
-	if err != nil {
-		return nil, err
-	}
-	Debug(3, string(bytes))
-	var data *ClusterInstalledAddons
-
-	err = json.Unmarshal(bytes, &data)
-	if err != nil {
-		return nil, err
+	if addons.Err() != nil {
+		return nil, addons.Err()
 	}
+	data.Count = addons.Count()
 
 	return data, nil
 }
@@ -1723,72 +982,23 @@ func (s *Client) GetClusterInstalledAddons(clusterUUID string) (*ClusterInstalle
 // IsAddonInstalled check if addon is installed (bool)
 func (s *Client) IsAddonInstalled(clusterUUID string, addonName string) (*bool, error) {
 
-	clusterAddons, err := s.GetClusterInstalledAddons(clusterUUID)
-
-	if err != nil {
-		return nil, err
-	}
-
-	for _, addon := range clusterAddons.Results {
+	addons := s.InstalledAddons(clusterUUID)
+	for addons.Next() {
+		addon := addons.Addon()
 		if addonName == addon.Name {
-			if addon.AddonStatus.Status == "INSTALLED" {
-				return Bool(true), nil
-			} else {
-				return Bool(false), nil
-			}
+			return Bool(addon.AddonStatus.Status == "INSTALLED"), nil
 		}
 	}
+	if addons.Err() != nil {
+		return nil, addons.Err()
+	}
 
 	return Bool(false), nil
-
 }
 
-// InstallAddonHXCSI Installs the Istio Operator
+// InstallAddonHXCSI installs the HyperFlex CSI driver with InstallAddon
 func (s *Client) InstallAddonHXCSI(clusterUUID string) error {
-	Debug(1, "Entered InstallAddonHXCSI for UUID "+clusterUUID)
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID is required")
-	}
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
-
-	Debug(2, "Getting Add-Ons catalog for UUID "+clusterUUID)
-	addons, err := s.GetAddonsCatalogue(clusterUUID)
-	if err != nil {
-		Debug(2, err.Error())
-		return err
-	}
-
-	if len(addons.CcpHxcsi.Name) == 0 {
-		return errors.New("hxcsi Addon not found in addons catalogue, can not install")
-	}
-
-	// fix missing Namespace from CcpHxcsi struct
-	addons.CcpHxcsi.Namespace = "ccp"
-	// now prepare the JSON body
-	jsonBody, err := json.Marshal(addons.CcpHxcsi)
-	if err != nil {
-		return err
-	}
-
-	Debug(2, "POSTing the JSON body:")
-	Debug(2, string(jsonBody))
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return err
-	}
-
-	resp, err := s.doRequest(req)
-	if err != nil {
-		return err
-	}
-	Debug(3, "Response is:")
-	Debug(3, string(resp))
-
-	Debug(2, "Request sent to API with success response")
-	return nil
+	return s.InstallAddon(clusterUUID, "ccp-hxcsi")
 }
 
 // DeleteAddonHXCSI deletes the addon
@@ -1817,43 +1027,9 @@ func (s *Client) DeleteAddonHXCSI(clusterUUID string) error {
 	return nil
 }
 
-// InstallAddonKubeflow Installs the Kubeflow addons
+// InstallAddonKubeflow installs Kubeflow with InstallAddon
 func (s *Client) InstallAddonKubeflow(clusterUUID string) error {
-	Debug(1, "Entered InstallAddonKubeflow for UUID "+clusterUUID)
-
-	if clusterUUID == "" {
-		return errors.New("Cluster UUID is required")
-	}
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/"
-
-	Debug(2, "Getting Add-Ons catalog for UUID "+clusterUUID)
-	addons, err := s.GetAddonsCatalogue(clusterUUID)
-	if err != nil {
-		Debug(2, err.Error())
-		return err
-	}
-	jsonBody, err := json.Marshal(addons.CcpKubeflow)
-	if err != nil {
-		return err
-	}
-
-	Debug(2, string(jsonBody))
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return err
-	}
-
-	resp, err := s.doRequest(req)
-	if err != nil {
-		return err
-	}
-	Debug(3, "Response is:")
-	Debug(3, string(resp))
-
-	Debug(2, "Request sent to API with success response")
-	return nil
+	return s.InstallAddon(clusterUUID, "ccp-kubeflow")
 }
 
 // GetKubeflowAddonConfig for kubeflow
@@ -1870,7 +1046,11 @@ func (s *Client) GetKubeflowAddonConfig(clusterUUID string) ([]byte, error) {
 		Debug(2, err.Error())
 		return nil, err
 	}
-	jsonBody, err := json.Marshal(addons.CcpKubeflow)
+	kubeflow, ok := addons.Find("ccp-kubeflow")
+	if !ok {
+		return nil, errors.New("kubeflow Addon not found in addons catalogue")
+	}
+	jsonBody, err := json.Marshal(kubeflow)
 	if err != nil {
 		return nil, err
 	}
@@ -1940,100 +1120,32 @@ func (s *Client) PatchCluster(cluster *Cluster, clusterUUID string) (*Cluster, e
 	return cluster, nil
 }
 
-// DeleteAddon delete an Addon
+// DeleteAddon deletes an addon and the installed addons that need it, waiting up to AddonDeleteTimeout for
+// them to go. The addon is looked up in the cluster catalogue with GetAddonRegistry, see DeleteAddonFromRegistry
 func (s *Client) DeleteAddon(clusterUUID string, addonName string) error {
 
 	if clusterUUID == "" {
 		return errors.New("Cluster UUID is required")
 	}
 
-	switch addonName {
-
-	case "kubernetes-dashboard", "ccp-efk", "ccp-monitor", "ccp-kubeflow":
-		err = s.DeleteAddonAndConfirm(clusterUUID, addonName)
-
-		if err != nil {
-			return err
-		}
-
-	case "istio":
-
-		err = s.DeleteAddonAndConfirm(clusterUUID, "ccp-istio-operator")
-
-		if err != nil {
-			return err
-		}
-
-		// err = s.DeleteAddonIstioInstance(clusterUUID)
-
-		// if err != nil {
-		// 	return err
-		// }
-
-		err = s.DeleteAddonAndConfirm(clusterUUID, "ccp-istio-cr")
-
-		if err != nil {
-			return err
-		}
-
-	case "harbor":
-
-		err = s.DeleteAddonAndConfirm(clusterUUID, "ccp-harbor-operator")
-
-		if err != nil {
-			return err
-		}
-
-		// err = s.DeleteAddonHarborInstance(clusterUUID)
-
-		// if err != nil {
-		// 	return err
-		// }
-
-		err = s.DeleteAddonAndConfirm(clusterUUID, "ccp-harbor-cr")
-
-		if err != nil {
-			return err
-		}
-
-	case "hxcsi", "hx-csi", "ccp-hxcsi":
-		err = s.DeleteAddonAndConfirm(clusterUUID, "ccp-hxcsi")
-
-		if err != nil {
-			return err
-		}
-
-	default:
-		return errors.New("Incorrect addon name provided for Delete: " + addonName + " Options are 'kubernetes-dashboard', 'ccp-efk', 'ccp-monitor', 'ccp-istio','harbor', 'ccp-kubeflow', 'hx-csi'")
-
-	}
-
-	return nil
-}
-
-// DeleteAddonAndConfirm delete and confirm
-func (s *Client) DeleteAddonAndConfirm(clusterUUID string, addonName string) error {
-
-	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/addons/" + addonName + "/"
-
-	addonInstalled, err := s.IsAddonInstalled(clusterUUID, addonName)
-
+	registry, err := s.GetAddonRegistry(clusterUUID)
 	if err != nil {
 		return err
 	}
 
-	if *addonInstalled {
+	ctx, cancel := context.WithTimeout(context.Background(), AddonDeleteTimeout)
+	defer cancel()
 
-		req, err := http.NewRequest("DELETE", url, nil)
-		if err != nil {
-			return err
-		}
-		_, err = s.doRequest(req)
-		if err != nil {
-			return err
-		}
+	_, err = s.DeleteAddonFromRegistry(ctx, clusterUUID, registry, addonName)
+	return err
+}
 
-	}
+// DeleteAddonAndConfirm deletes an addon and waits up to AddonDeleteTimeout until it is no longer listed.
+// Only this addon is deleted, DeleteAddon deletes the addons that need it too
+func (s *Client) DeleteAddonAndConfirm(clusterUUID string, addonName string) error {
 
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), AddonDeleteTimeout)
+	defer cancel()
+
+	return s.deleteAddonAndWait(ctx, clusterUUID, addonName)
 }
@@ -0,0 +1,101 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAddonsCatalogueFind(t *testing.T) {
+
+	catalog := testCatalog()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"_ccp-monitor", "ccp-monitor"},
+		{"ccp-monitor", "ccp-monitor"},
+		{"ccp-istio-cr", "ccp-istio-cr"},
+		{"_ccp-istio", "ccp-istio-cr"},
+		{"ccp-istio", "ccp-istio-cr"},
+		{"monitoring", ""},
+	}
+
+	for _, test := range tests {
+		entry, ok := catalog.Find(test.name)
+		if ok != (test.want != "") || (ok && entry.Name != test.want) {
+			t.Errorf("Find(%q) = %v, %v, want %q", test.name, entry, ok, test.want)
+		}
+	}
+}
+
+func TestAddonsCatalogueKeys(t *testing.T) {
+
+	want := []string{
+		"_ccp-efk",
+		"_ccp-harbor-operator",
+		"_ccp-istio-operator",
+		"_ccp-kubeflow",
+		"_ccp-monitor",
+		"_kubernetes-dashboard",
+	}
+	if keys := testCatalog().Keys(); !reflect.DeepEqual(keys, want) {
+		t.Errorf("Keys() = %v, want %v", keys, want)
+	}
+	if keys := (AddonsCatalogue{}).Keys(); len(keys) != 0 {
+		t.Errorf("Keys() of an empty catalogue = %v", keys)
+	}
+}
+
+func TestGetAddonsCatalogueKeepsNewAddons(t *testing.T) {
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/clusters/cluster-1/catalog" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+			"_ccp-monitor": {"name": "ccp-monitor", "url": "/opt/ccp/charts/ccp-monitor.tgz"},
+			"_ccp-new": {"name": "ccp-new", "namespace": "tools", "url": "/opt/ccp/charts/ccp-new.tgz",
+				"overrideFiles": ["/opt/ccp/charts/ccp-new.yaml"], "conflicts": ["ccp-monitor"],
+				"dependencies": {"_ccp-new-cr": {"name": "ccp-new-cr", "url": "/opt/ccp/charts/ccp-new-cr.tgz"}}}
+		}`))
+	}))
+
+	catalog, err := client.GetAddonsCatalogue("cluster-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := catalog["_ccp-new"]
+	if !ok {
+		t.Fatalf("catalogue %v has no _ccp-new", catalog.Keys())
+	}
+	want := CatalogEntry{
+		Name:          "ccp-new",
+		Namespace:     "tools",
+		URL:           "/opt/ccp/charts/ccp-new.tgz",
+		OverrideFiles: []string{"/opt/ccp/charts/ccp-new.yaml"},
+		Conflicts:     []string{"ccp-monitor"},
+		Dependencies:  AddonsCatalogue{"_ccp-new-cr": {Name: "ccp-new-cr", URL: "/opt/ccp/charts/ccp-new-cr.tgz"}},
+	}
+	if !reflect.DeepEqual(entry, want) {
+		t.Errorf("_ccp-new = %+v, want %+v", entry, want)
+	}
+}
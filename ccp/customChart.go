@@ -0,0 +1,124 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/validator.v2"
+)
+
+// ChartDirectory is where CCP reads addon charts and override files on the control plane
+const ChartDirectory = "/opt/ccp/charts/"
+
+// ChartSpec is a Helm chart to install through the CCP addons endpoint. URL is the chart path on the control
+// plane, which must be under ChartDirectory, e.g. /opt/ccp/charts/custom/mychart.tgz, as must OverrideFiles.
+// Name is the Helm release name and Namespace defaults to DefaultAddonNamespace. Values are optional Helm
+// values, YAML or JSON
+type ChartSpec struct {
+	Name          string   `json:"name" validate:"nonzero,max=53"`
+	Namespace     string   `json:"namespace,omitempty" validate:"max=63"`
+	URL           string   `json:"url" validate:"nonzero"`
+	DisplayName   string   `json:"displayName,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	OverrideFiles []string `json:"overrideFiles,omitempty"`
+	Values        []byte   `json:"-"`
+}
+
+// chartName is what Helm and Kubernetes accept for release and namespace names
+var chartName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// InstallCustomChart installs a chart which is not in the addon catalogue and waits for it with WaitForAddon, so
+// it fails or times out like a built-in addon. Once installed it is listed with the other addons and is removed
+// with DeleteAddon. A chart with the name of a catalogue addon is refused, as is one already installed from a
+// different chart. A chart already installed from the same URL is waited for and not posted again
+func (s *Client) InstallCustomChart(ctx context.Context, clusterUUID string, chart ChartSpec) error {
+	Debug(1, "Entered InstallCustomChart for UUID "+clusterUUID+" chart "+chart.Name)
+
+	if clusterUUID == "" {
+		return errors.New("Cluster UUID is required")
+	}
+
+	if chart.Namespace == "" {
+		chart.Namespace = DefaultAddonNamespace
+	}
+	errs := validator.Validate(chart)
+	if errs != nil {
+		return errors.New("Chart " + chart.Name + " is not valid: " + errs.Error())
+	}
+	if !chartName.MatchString(chart.Name) {
+		return errors.New("Chart name " + chart.Name + " must be lower case letters, digits and -")
+	}
+	if !chartName.MatchString(chart.Namespace) {
+		return errors.New("Chart namespace " + chart.Namespace + " must be lower case letters, digits and -")
+	}
+	for _, file := range append([]string{chart.URL}, chart.OverrideFiles...) {
+		if !isChartPath(file) {
+			return errors.New("Chart file " + file + " must be an absolute path under " + ChartDirectory + " on the control plane")
+		}
+	}
+
+	entry := CatalogEntry{
+		DisplayName:   chart.DisplayName,
+		Name:          chart.Name,
+		Namespace:     chart.Namespace,
+		Description:   chart.Description,
+		URL:           chart.URL,
+		OverrideFiles: chart.OverrideFiles,
+	}
+	if entry.DisplayName == "" {
+		entry.DisplayName = chart.Name
+	}
+	if chart.Values != nil {
+		values, err := ParseAddonValues(chart.Values)
+		if err != nil {
+			return err
+		}
+		entry.Overrides, err = MergeAddonValues(&entry, values)
+		if err != nil {
+			return err
+		}
+	}
+
+	registry, err := s.GetAddonRegistry(clusterUUID)
+	if err != nil {
+		return err
+	}
+	if name, ok := registry.Resolve(chart.Name); ok {
+		return errors.New("Chart name " + chart.Name + " is the catalogue addon " + name + ", use InstallAddon")
+	}
+
+	installed, err := s.GetClusterAddon(clusterUUID, chart.Name)
+	if err == nil {
+		if installed.AddonStatus != nil && installed.AddonStatus.URLInstalled != nil && *installed.AddonStatus.URLInstalled != chart.URL {
+			return errors.New("Chart " + chart.Name + " is already installed from " + *installed.AddonStatus.URLInstalled)
+		}
+	} else if !errors.Is(err, ErrAddonNotInstalled) {
+		return err
+	}
+
+	return s.installAddonEntry(ctx, clusterUUID, entry)
+}
+
+// isChartPath reports whether file is a clean absolute path under ChartDirectory, so it cannot be a URL or climb
+// out of the directory with ..
+func isChartPath(file string) bool {
+	return path.IsAbs(file) && path.Clean(file) == file && strings.HasPrefix(file, ChartDirectory)
+}
@@ -0,0 +1,110 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsChartPath(t *testing.T) {
+
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"/opt/ccp/charts/myapp.tgz", true},
+		{"/opt/ccp/charts/custom/myapp.tgz", true},
+		{"/opt/ccp/charts/custom/values.yaml", true},
+		{"opt/ccp/charts/myapp.tgz", false},
+		{"/opt/ccp/charts/../../../etc/passwd", false},
+		{"/opt/ccp/charts//myapp.tgz", false},
+		{"/opt/ccp/chartsx/myapp.tgz", false},
+		{"/tmp/myapp.tgz", false},
+		{"https://charts.example.com/myapp.tgz", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := isChartPath(test.file); got != test.want {
+			t.Errorf("isChartPath(%q) = %v, want %v", test.file, got, test.want)
+		}
+	}
+}
+
+func TestInstallCustomChart(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	tests := []struct {
+		name  string
+		chart ChartSpec
+		posts []string
+		err   string
+	}{
+		{"chart", ChartSpec{Name: "myapp", URL: "/opt/ccp/charts/custom/myapp.tgz"}, []string{"myapp"}, ""},
+		{"already installed", ChartSpec{Name: "tools", URL: "/opt/ccp/charts/custom/tools.tgz"}, nil, ""},
+		{"other chart installed", ChartSpec{Name: "tools", URL: "/opt/ccp/charts/custom/tools-2.tgz"}, nil, "already installed from"},
+		{"catalogue name", ChartSpec{Name: "monitoring", URL: "/opt/ccp/charts/custom/monitor.tgz"}, nil, "catalogue addon ccp-monitor"},
+		{"no name", ChartSpec{URL: "/opt/ccp/charts/custom/myapp.tgz"}, nil, "is not valid"},
+		{"upper case", ChartSpec{Name: "MyApp", URL: "/opt/ccp/charts/custom/myapp.tgz"}, nil, "lower case"},
+		{"namespace", ChartSpec{Name: "myapp", Namespace: "My_Apps", URL: "/opt/ccp/charts/custom/myapp.tgz"}, nil, "lower case"},
+		{"http url", ChartSpec{Name: "myapp", URL: "https://charts.example.com/myapp.tgz"}, nil, "under /opt/ccp/charts/"},
+		{"outside charts", ChartSpec{Name: "myapp", URL: "/opt/ccp/charts/../secrets.tgz"}, nil, "under /opt/ccp/charts/"},
+		{"override file", ChartSpec{Name: "myapp", URL: "/opt/ccp/charts/custom/myapp.tgz", OverrideFiles: []string{"/etc/myapp.yaml"}}, nil, "/etc/myapp.yaml"},
+		{"bad values", ChartSpec{Name: "myapp", URL: "/opt/ccp/charts/custom/myapp.tgz", Values: []byte("- a\n")}, nil, "Cannot read addon values"},
+	}
+
+	for _, test := range tests {
+		server := &addonServer{catalog: testCatalog()}
+		server.install("tools", "/opt/ccp/charts/custom/tools.tgz", "INSTALLED")
+		client := newTestClient(t, server)
+
+		err := client.InstallCustomChart(context.Background(), "cluster-1", test.chart)
+		if test.err == "" && err != nil {
+			t.Errorf("%s: error %v", test.name, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+		if !reflect.DeepEqual(server.posts, test.posts) {
+			t.Errorf("%s: posted %v, want %v", test.name, server.posts, test.posts)
+		}
+	}
+}
+
+func TestInstallCustomChartValues(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	server := &addonServer{catalog: testCatalog()}
+	client := newTestClient(t, server)
+
+	chart := ChartSpec{Name: "myapp", URL: "/opt/ccp/charts/custom/myapp.tgz", Values: []byte(`{"replicas": 3}`)}
+	err := client.InstallCustomChart(context.Background(), "cluster-1", chart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addon, err := client.GetClusterAddon("cluster-1", "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addon.Overrides == nil || *addon.Overrides != "replicas: 3\n" {
+		t.Errorf("posted overrides %v, want replicas: 3", addon.Overrides)
+	}
+}
@@ -0,0 +1,310 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ChangeKind says how a change to a cluster can be made
+type ChangeKind string
+
+const (
+	// ChangeInPlace is made with PatchCluster, or AddNodePool for a worker pool which is only in the spec
+	ChangeInPlace ChangeKind = "update"
+	// ChangeScale is a worker pool size, made with ScaleCluster
+	ChangeScale ChangeKind = "scale"
+	// ChangeUpgrade is a Kubernetes version or template the cluster can be upgraded to, made with UpgradeCluster
+	ChangeUpgrade ChangeKind = "upgrade"
+	// ChangeRecreate cannot be made to the live cluster, it has to be deleted and created again
+	ChangeRecreate ChangeKind = "recreate"
+	// ChangeUnmanaged is a live worker pool which is not in the spec. It is reported but left alone, so it is
+	// not a pending change
+	ChangeUnmanaged ChangeKind = "unmanaged"
+)
+
+// ClusterChange is one field which differs between a desired and a live cluster. Path uses the JSON names,
+// with worker pools selected by name, e.g. "node_groups[pool1].size". Old is nil for an added worker pool
+// and New is nil for a removed one
+type ClusterChange struct {
+	Path string      `json:"path"`
+	Kind ChangeKind  `json:"kind"`
+	Pool string      `json:"pool,omitempty"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// ClusterDiff is the difference between a desired cluster and the live one. Create is set when there is no
+// live cluster, in which case there are no Changes
+type ClusterDiff struct {
+	Cluster string          `json:"cluster"`
+	UUID    string          `json:"id,omitempty"`
+	Create  bool            `json:"create"`
+	Changes []ClusterChange `json:"changes,omitempty"`
+}
+
+// Empty checks if the live cluster already matches. Unmanaged worker pools do not count
+func (d *ClusterDiff) Empty() bool {
+	if d.Create {
+		return false
+	}
+	for _, change := range d.Changes {
+		if change.Kind != ChangeUnmanaged {
+			return false
+		}
+	}
+	return true
+}
+
+// RequiresRecreate checks if any change needs the cluster to be recreated
+func (d *ClusterDiff) RequiresRecreate() bool {
+	for _, change := range d.Changes {
+		if change.Kind == ChangeRecreate {
+			return true
+		}
+	}
+	return false
+}
+
+// diffIgnoredFields are set by CCP, not by the user, and never compared
+var diffIgnoredFields = map[string]bool{
+	"UUID":       true,
+	"Status":     true,
+	"Nodes":      true,
+	"KubeConfig": true,
+}
+
+// DiffCluster compares a desired cluster with the live one, e.g. from GetClusterByUUID. Fields which are
+// not set in desired are not compared, so a spec only needs the fields it cares about. A nil live cluster
+// gives a diff which creates the cluster
+func DiffCluster(desired, live *Cluster) *ClusterDiff {
+
+	diff := ClusterDiff{}
+	if desired.Name != nil {
+		diff.Cluster = *desired.Name
+	}
+	if live == nil {
+		diff.Create = true
+		return &diff
+	}
+	if live.UUID != nil {
+		diff.UUID = *live.UUID
+	}
+	if diff.Cluster == "" && live.Name != nil {
+		diff.Cluster = *live.Name
+	}
+
+	desiredValue := reflect.ValueOf(desired).Elem()
+	liveValue := reflect.ValueOf(live).Elem()
+
+	for i := 0; i < desiredValue.NumField(); i++ {
+		field := desiredValue.Type().Field(i)
+		if diffIgnoredFields[field.Name] || field.Name == "WorkerNodePool" {
+			continue
+		}
+		kind := ChangeRecreate
+		for _, patchable := range patchableClusterFields {
+			if field.Name == patchable {
+				kind = ChangeInPlace
+			}
+		}
+		diffValue(&diff, jsonName(field), desiredValue.Field(i), liveValue.Field(i), kind)
+	}
+
+	diffWorkerNodePools(&diff, desired, live)
+
+	for i, change := range diff.Changes {
+		if isUpgradePath(change.Path) {
+			diff.Changes[i].Kind = upgradeKind(change)
+		}
+	}
+
+	return &diff
+}
+
+// isUpgradePath checks if the change is to a Kubernetes version or template, which UpgradeCluster changes
+func isUpgradePath(path string) bool {
+	switch path {
+	case "kubernetes_version", "master_group.kubernetes_version", "master_group.template":
+		return true
+	}
+	return strings.HasPrefix(path, "node_groups[") && (strings.HasSuffix(path, "].kubernetes_version") || strings.HasSuffix(path, "].template"))
+}
+
+// upgradeKind is ChangeUpgrade when CheckUpgradePath allows moving from the old to the new version, or the
+// versions in the old and new template names. Anything else, such as a downgrade, needs recreation
+func upgradeKind(change ClusterChange) ChangeKind {
+
+	from, _ := change.Old.(string)
+	to, _ := change.New.(string)
+	if strings.HasSuffix(change.Path, "template") {
+		from = GetKubeVerFromImage(from)
+		to = GetKubeVerFromImage(to)
+	}
+
+	if from == "" || to == "" || CheckUpgradePath(from, to) != nil {
+		return ChangeRecreate
+	}
+	return ChangeUpgrade
+}
+
+// diffWorkerNodePools matches the worker pools by name. Pool sizes scale, pools only in the spec are added in
+// place, live pools not in the spec are unmanaged and any other pool change needs recreation
+func diffWorkerNodePools(diff *ClusterDiff, desired, live *Cluster) {
+
+	if desired.WorkerNodePool == nil {
+		return
+	}
+
+	for _, pool := range *desired.WorkerNodePool {
+		poolName := ""
+		if pool.Name != nil {
+			poolName = *pool.Name
+		}
+		path := "node_groups[" + poolName + "]"
+
+		livePool := findWorkerNodePool(live, poolName)
+		if livePool == nil {
+			diff.Changes = append(diff.Changes, ClusterChange{Path: path, Kind: ChangeInPlace, Pool: poolName, New: pool})
+			continue
+		}
+
+		before := len(diff.Changes)
+		poolValue := reflect.ValueOf(pool)
+		livePoolValue := reflect.ValueOf(*livePool)
+		for i := 0; i < poolValue.NumField(); i++ {
+			field := poolValue.Type().Field(i)
+			if diffIgnoredFields[field.Name] || field.Name == "Name" {
+				continue
+			}
+			kind := ChangeRecreate
+			if field.Name == "Size" {
+				kind = ChangeScale
+			}
+			diffValue(diff, path+"."+jsonName(field), poolValue.Field(i), livePoolValue.Field(i), kind)
+		}
+		for i := before; i < len(diff.Changes); i++ {
+			diff.Changes[i].Pool = poolName
+		}
+	}
+
+	if live.WorkerNodePool == nil {
+		return
+	}
+	for _, pool := range *live.WorkerNodePool {
+		if pool.Name == nil || findWorkerNodePool(desired, *pool.Name) != nil {
+			continue
+		}
+		diff.Changes = append(diff.Changes, ClusterChange{Path: "node_groups[" + *pool.Name + "]", Kind: ChangeUnmanaged, Pool: *pool.Name, Old: pool})
+	}
+}
+
+// diffValue compares a desired and live pointer field, descending into structs so only the fields set in
+// desired are compared. Patchable structs are compared whole since PatchCluster replaces them
+func diffValue(diff *ClusterDiff, path string, want, have reflect.Value, kind ChangeKind) {
+
+	if want.IsNil() {
+		return // not in the spec, leave it alone
+	}
+
+	if want.Elem().Kind() == reflect.Struct && kind != ChangeInPlace {
+		haveElem := reflect.Zero(want.Elem().Type())
+		if !have.IsNil() {
+			haveElem = have.Elem()
+		}
+		for i := 0; i < want.Elem().NumField(); i++ {
+			field := want.Elem().Type().Field(i)
+			if diffIgnoredFields[field.Name] {
+				continue
+			}
+			diffValue(diff, path+"."+jsonName(field), want.Elem().Field(i), haveElem.Field(i), kind)
+		}
+		return
+	}
+
+	if sameValue(want, have) {
+		return
+	}
+
+	change := ClusterChange{Path: path, Kind: kind, New: want.Elem().Interface()}
+	if !have.IsNil() {
+		change.Old = have.Elem().Interface()
+	}
+	diff.Changes = append(diff.Changes, change)
+}
+
+// sameValue compares two pointer fields, treating nil and empty slices as the same
+func sameValue(a, b reflect.Value) bool {
+	if isEmptyValue(a) && isEmptyValue(b) {
+		return true
+	}
+	if a.IsNil() || b.IsNil() {
+		return false
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	if v.IsNil() {
+		return true
+	}
+	if v.Elem().Kind() == reflect.Slice {
+		return v.Elem().Len() == 0
+	}
+	return false
+}
+
+// jsonName is the JSON name of a struct field
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// DiffClusterSpec compares a desired cluster with the live cluster of the same UUID, or of the same name
+// when desired has no UUID. The diff creates the cluster when there is no live cluster
+func (s *Client) DiffClusterSpec(desired *Cluster) (*ClusterDiff, error) {
+
+	if desired == nil || (desired.UUID == nil && desired.Name == nil) {
+		return nil, errors.New("Cluster name or UUID is required")
+	}
+
+	clusterUUID := ""
+	if desired.UUID != nil {
+		clusterUUID = *desired.UUID
+	} else {
+		Debug(1, "Entered DiffClusterSpec for "+*desired.Name)
+		cluster, err := s.findClusterByName(*desired.Name)
+		if err != nil {
+			return nil, err
+		}
+		if cluster == nil {
+			return DiffCluster(desired, nil), nil
+		}
+		clusterUUID = *cluster.UUID
+	}
+
+	live, err := s.GetClusterByUUID(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffCluster(desired, live), nil
+}
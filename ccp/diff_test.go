@@ -0,0 +1,166 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"testing"
+)
+
+// liveCluster is a cluster as returned by CCP, with fields the user never sets
+func liveCluster() *Cluster {
+	return &Cluster{
+		UUID:              String("uuid"),
+		Name:              String("mycluster"),
+		Status:            String("READY"),
+		KubernetesVersion: String("1.15.5"),
+		Description:       String("old"),
+		NTPServers:        &[]string{},
+		MasterNodePool: &MasterNodePool{
+			Size:              Int64(1),
+			Template:          String(upgradeFrom),
+			KubernetesVersion: String("1.15.5"),
+			Nodes:             &[]Node{{Name: String("master-a")}},
+		},
+		WorkerNodePool: &[]WorkerNodePool{
+			{Name: String("workers"), Size: Int64(2), Template: String(upgradeFrom), VCPUs: Int64(8)},
+			{Name: String("gpu"), Size: Int64(1), Template: String(upgradeFrom)},
+		},
+	}
+}
+
+func TestDiffCluster(t *testing.T) {
+
+	type change struct {
+		path string
+		kind ChangeKind
+	}
+
+	tests := []struct {
+		name    string
+		desired Cluster
+		changes []change
+	}{
+		{
+			"only name",
+			Cluster{Name: String("mycluster")},
+			nil,
+		},
+		{
+			"same values and empty lists",
+			Cluster{Name: String("mycluster"), Description: String("old"), NTPServers: &[]string{}, KubernetesVersion: String("1.15.5")},
+			nil,
+		},
+		{
+			"patch",
+			Cluster{Name: String("mycluster"), Description: String("new"), NTPServers: &[]string{"ntp1"}},
+			[]change{{"ntp_servers", ChangeInPlace}, {"description", ChangeInPlace}},
+		},
+		{
+			"recreate",
+			Cluster{Name: String("mycluster"), SubnetUUID: String("subnet"), MasterNodePool: &MasterNodePool{VCPUs: Int64(4)}},
+			[]change{{"subnet_id", ChangeRecreate}, {"master_group.vcpus", ChangeRecreate}},
+		},
+		{
+			"upgrade",
+			Cluster{Name: String("mycluster"), KubernetesVersion: String("1.16.3"), MasterNodePool: &MasterNodePool{Template: String(upgradeTo)}},
+			[]change{{"kubernetes_version", ChangeUpgrade}, {"master_group.template", ChangeUpgrade}},
+		},
+		{
+			"downgrade",
+			Cluster{Name: String("mycluster"), KubernetesVersion: String("1.14.1")},
+			[]change{{"kubernetes_version", ChangeRecreate}},
+		},
+		{
+			"minor version skip",
+			Cluster{Name: String("mycluster"), MasterNodePool: &MasterNodePool{Template: String("ccp-tenant-image-1.17.0-ubuntu18-6.1.1")}},
+			[]change{{"master_group.template", ChangeRecreate}},
+		},
+		{
+			"worker pools",
+			Cluster{Name: String("mycluster"), WorkerNodePool: &[]WorkerNodePool{
+				{Name: String("workers"), Size: Int64(3), VCPUs: Int64(16), Template: String(upgradeTo)},
+				{Name: String("new"), Size: Int64(1)},
+			}},
+			[]change{
+				{"node_groups[workers].size", ChangeScale},
+				{"node_groups[workers].template", ChangeUpgrade},
+				{"node_groups[workers].vcpus", ChangeRecreate},
+				{"node_groups[new]", ChangeInPlace},
+				{"node_groups[gpu]", ChangeUnmanaged},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		diff := DiffCluster(&test.desired, liveCluster())
+
+		if diff.Create || diff.UUID != "uuid" || diff.Cluster != "mycluster" {
+			t.Errorf("%s: diff %+v", test.name, diff)
+		}
+		if len(diff.Changes) != len(test.changes) {
+			t.Errorf("%s: changes %+v, want %v", test.name, diff.Changes, test.changes)
+			continue
+		}
+		for i, want := range test.changes {
+			got := diff.Changes[i]
+			if got.Path != want.path || got.Kind != want.kind {
+				t.Errorf("%s: change %d is %s %s, want %s %s", test.name, i, got.Path, got.Kind, want.path, want.kind)
+			}
+		}
+	}
+}
+
+func TestDiffClusterEmpty(t *testing.T) {
+
+	if diff := DiffCluster(&Cluster{Name: String("new")}, nil); !diff.Create || diff.Empty() {
+		t.Errorf("diff with no live cluster = %+v", diff)
+	}
+
+	// a spec listing only some of the pools converges, the others are left alone
+	desired := &Cluster{Name: String("mycluster"), WorkerNodePool: &[]WorkerNodePool{{Name: String("workers"), Size: Int64(2)}}}
+	diff := DiffCluster(desired, liveCluster())
+	if !diff.Empty() || diff.RequiresRecreate() || len(diff.Changes) != 1 {
+		t.Errorf("diff with an unmanaged pool = %+v", diff)
+	}
+
+	desired.Description = String("new")
+	if DiffCluster(desired, liveCluster()).Empty() {
+		t.Error("diff with a description change is empty")
+	}
+}
+
+func TestUpgradeTemplate(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		upgrades []ClusterChange
+		want     string
+		wantErr  bool
+	}{
+		{"master template", []ClusterChange{{Path: "kubernetes_version", New: "1.16.3"}, {Path: "master_group.template", New: upgradeTo}}, upgradeTo, false},
+		{"pool template", []ClusterChange{{Path: "node_groups[workers].template", New: upgradeTo}}, upgradeTo, false},
+		{"no template", []ClusterChange{{Path: "kubernetes_version", New: "1.16.3"}}, "", true},
+		{"version differs", []ClusterChange{{Path: "kubernetes_version", New: "1.16.1"}, {Path: "master_group.template", New: upgradeTo}}, "", true},
+		{"two templates", []ClusterChange{{Path: "master_group.template", New: upgradeTo}, {Path: "node_groups[workers].template", New: "ccp-tenant-image-1.16.1-ubuntu18-6.1.1"}}, "", true},
+	}
+
+	for _, test := range tests {
+		got, err := upgradeTemplate(test.upgrades)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("%s: upgradeTemplate = %q, %v", test.name, got, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"encoding/json"
+	"errors"
+
+	"gopkg.in/validator.v2"
+)
+
+// ExportClusterSpec returns a copy of a live cluster that can be saved and given to ConvertJSONToCluster and
+// AddCluster to create the cluster again. The fields CCP sets are removed: UUID, Status, KubeConfig, the master
+// VIP and the nodes of every pool. SSH public keys are removed too unless keepSSHKeys is set. An error is
+// returned if the spec would not pass AddCluster validation
+func ExportClusterSpec(cluster *Cluster, keepSSHKeys bool) (*Cluster, error) {
+
+	if cluster == nil || cluster.Name == nil {
+		return nil, errors.New("Cluster with a name is required")
+	}
+	Debug(1, "Entered ExportClusterSpec for "+*cluster.Name)
+
+	// copy through JSON so the live cluster is not changed
+	jsonBody, err := json.Marshal(cluster)
+	if err != nil {
+		return nil, err
+	}
+	var spec Cluster
+	err = json.Unmarshal(jsonBody, &spec)
+	if err != nil {
+		return nil, err
+	}
+
+	spec.UUID = nil
+	spec.Status = nil
+	spec.KubeConfig = nil
+	spec.MasterVIP = nil
+
+	if spec.MasterNodePool != nil {
+		spec.MasterNodePool.Nodes = nil
+		if !keepSSHKeys {
+			spec.MasterNodePool.SSHKey = nil
+		}
+	}
+	if spec.WorkerNodePool != nil {
+		for i := range *spec.WorkerNodePool {
+			pool := &(*spec.WorkerNodePool)[i]
+			pool.Nodes = nil
+			if !keepSSHKeys {
+				pool.SSHKey = nil
+			}
+		}
+	}
+
+	errs := validator.Validate(spec)
+	if errs != nil {
+		return nil, errors.New("Exported cluster " + *cluster.Name + " would not pass validation: " + errs.Error())
+	}
+
+	return &spec, nil
+}
+
+// ExportClusterSpecJSON is ExportClusterSpec marshaled as indented JSON, ready to be written to a file
+func ExportClusterSpecJSON(cluster *Cluster, keepSSHKeys bool) ([]byte, error) {
+
+	spec, err := ExportClusterSpec(cluster, keepSSHKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(spec, "", "  ")
+}
@@ -0,0 +1,131 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// exportCluster is a live cluster with every field AddCluster needs and the fields CCP sets
+func exportCluster() *Cluster {
+	nodes := &[]Node{{Name: String("node-a"), Status: String("READY")}}
+	return &Cluster{
+		UUID:               String("uuid"),
+		Name:               String("mycluster"),
+		Status:             String("READY"),
+		KubeConfig:         String("apiVersion: v1"),
+		MasterVIP:          String("10.1.1.10"),
+		KubernetesVersion:  String("1.16.3"),
+		IPAllocationMethod: String("ccpipam"),
+		Infra: &Infra{
+			Datacenter: String("dc"),
+			Datastore:  String("ds"),
+			Cluster:    String("cl"),
+			Networks:   &[]string{"vm-network"},
+		},
+		MasterNodePool: &MasterNodePool{
+			Size:     Int64(1),
+			Template: String(upgradeTo),
+			VCPUs:    Int64(2),
+			Memory:   Int64(8192),
+			SSHKey:   String("ssh-rsa AAAA"),
+			Nodes:    nodes,
+		},
+		WorkerNodePool: &[]WorkerNodePool{{
+			Name:     String("workers"),
+			Size:     Int64(2),
+			Template: String(upgradeTo),
+			VCPUs:    Int64(8),
+			Memory:   Int64(32768),
+			SSHKey:   String("ssh-rsa AAAA"),
+			Nodes:    nodes,
+		}},
+		NetworkPlugin: &NetworkPlugin{Name: String("calico")},
+	}
+}
+
+func TestExportClusterSpec(t *testing.T) {
+
+	tests := []struct {
+		keepSSHKeys bool
+		sshKey      bool
+	}{
+		{false, false},
+		{true, true},
+	}
+
+	for _, test := range tests {
+		live := exportCluster()
+		spec, err := ExportClusterSpec(live, test.keepSSHKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if spec.UUID != nil || spec.Status != nil || spec.KubeConfig != nil || spec.MasterVIP != nil {
+			t.Errorf("keepSSHKeys %v: CCP fields were exported", test.keepSSHKeys)
+		}
+		if spec.MasterNodePool.Nodes != nil || (*spec.WorkerNodePool)[0].Nodes != nil {
+			t.Errorf("keepSSHKeys %v: nodes were exported", test.keepSSHKeys)
+		}
+		if (spec.MasterNodePool.SSHKey != nil) != test.sshKey || ((*spec.WorkerNodePool)[0].SSHKey != nil) != test.sshKey {
+			t.Errorf("keepSSHKeys %v: SSH keys exported is not %v", test.keepSSHKeys, test.sshKey)
+		}
+		if live.UUID == nil || live.MasterNodePool.Nodes == nil || live.MasterNodePool.SSHKey == nil {
+			t.Errorf("keepSSHKeys %v: the live cluster was changed", test.keepSSHKeys)
+		}
+
+		// the export diffs clean against the cluster it came from
+		if diff := DiffCluster(spec, live); !diff.Empty() {
+			t.Errorf("keepSSHKeys %v: export differs from the live cluster: %+v", test.keepSSHKeys, diff.Changes)
+		}
+	}
+}
+
+func TestExportClusterSpecInvalid(t *testing.T) {
+
+	live := exportCluster()
+	live.Infra = nil
+	_, err := ExportClusterSpec(live, false)
+	if err == nil || !strings.Contains(err.Error(), "would not pass validation") {
+		t.Errorf("ExportClusterSpec of a cluster without infra = %v", err)
+	}
+
+	_, err = ExportClusterSpec(&Cluster{}, false)
+	if err == nil {
+		t.Error("ExportClusterSpec of a cluster without a name did not fail")
+	}
+}
+
+func TestExportClusterSpecJSON(t *testing.T) {
+
+	data, err := ExportClusterSpecJSON(exportCluster(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"id"`, `"status"`, `"kubeconfig"`, `"master_vip"`, `"nodes"`, `"ssh_key"`} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("exported JSON has %s", field)
+		}
+	}
+
+	var spec Cluster
+	err = json.Unmarshal(data, &spec)
+	if err != nil || spec.Name == nil || *spec.Name != "mycluster" {
+		t.Errorf("exported JSON does not read back: %v", err)
+	}
+}
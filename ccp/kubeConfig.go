@@ -0,0 +1,135 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"errors"
+	"os"
+
+	"github.com/CiscoSE/ccp-client-library/ccp/kubeconfig"
+)
+
+// ClusterKubeConfig parses the kubeconfig of a cluster and renames its cluster, user and context
+// entries to the CCP cluster name. Only the inline certificate data is kept, see kubeconfig.ParseInline
+func ClusterKubeConfig(cluster *Cluster) (*kubeconfig.Config, error) {
+
+	if cluster == nil || cluster.Name == nil {
+		return nil, errors.New("Cluster name is required")
+	}
+	if cluster.KubeConfig == nil || *cluster.KubeConfig == "" {
+		return nil, errors.New("Cluster " + *cluster.Name + " has no kubeconfig, is it still being created?")
+	}
+
+	config, err := kubeconfig.ParseInline([]byte(*cluster.KubeConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.Rename(*cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// MergeClusterKubeConfig merges the kubeconfig of a cluster into the kubectl config file at path, or the default
+// kubectl config file when path is empty, without changing the other contexts in the file. When setContext is true
+// the cluster becomes the current context. The path written to is returned
+func MergeClusterKubeConfig(cluster *Cluster, path string, setContext bool) (string, error) {
+
+	config, err := ClusterKubeConfig(cluster)
+	if err != nil {
+		return "", err
+	}
+
+	if path == "" {
+		path = kubeconfig.DefaultPath()
+	}
+	Debug(2, "Merging kubeconfig for cluster "+*cluster.Name+" into "+path)
+
+	return path, kubeconfig.MergeIntoFile(path, config, setContext)
+}
+
+// RemoveClusterKubeConfig removes the context of a cluster from the kubectl config file at path, or the default
+// kubectl config file when path is empty. Use this after deleting a cluster. It returns false if there was nothing to remove
+func RemoveClusterKubeConfig(clusterName string, path string) (bool, error) {
+
+	if path == "" {
+		path = kubeconfig.DefaultPath()
+	}
+	Debug(2, "Removing kubeconfig for cluster "+clusterName+" from "+path)
+
+	return kubeconfig.RemoveFromFile(path, clusterName)
+}
+
+// RemoveClusterKubeConfigs removes the context of a cluster from each of the kubectl config files in paths. Only
+// pass files the cluster was merged into, a context of the same name in another file may be for another cluster.
+// Files which do not exist are skipped. It returns the files the context was removed from
+func RemoveClusterKubeConfigs(clusterName string, paths []string) ([]string, error) {
+
+	var removedFrom []string
+	seen := map[string]bool{}
+	for _, path := range paths {
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+
+		removed, err := RemoveClusterKubeConfig(clusterName, path)
+		if err != nil {
+			return removedFrom, errors.New("Cannot remove context " + clusterName + " from " + path + ": " + err.Error())
+		}
+		if removed {
+			removedFrom = append(removedFrom, path)
+		}
+	}
+
+	return removedFrom, nil
+}
+
+// FindClusterKubeConfigs returns the kubectl config files in paths, or in kubeconfig.SearchPaths when paths is
+// empty, which have a context named clusterName. The files are only read, so this can list the contexts left
+// behind by a deleted cluster without removing entries which were not written for it
+func FindClusterKubeConfigs(clusterName string, paths []string) ([]string, error) {
+
+	if len(paths) == 0 {
+		paths = kubeconfig.SearchPaths()
+	}
+
+	var found []string
+	seen := map[string]bool{}
+	for _, path := range paths {
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		config, err := kubeconfig.LoadFile(path)
+		if err != nil {
+			return found, errors.New("Cannot read " + path + ": " + err.Error())
+		}
+		if config.Context(clusterName) != nil {
+			found = append(found, path)
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,131 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CiscoSE/ccp-client-library/ccp/kubeconfig"
+)
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: kubernetes
+  cluster:
+    server: https://10.1.1.10:6443
+users:
+- name: kubernetes-admin
+  user:
+    token: abc
+contexts:
+- name: kubernetes-admin@kubernetes
+  context:
+    cluster: kubernetes
+    user: kubernetes-admin
+current-context: kubernetes-admin@kubernetes
+`
+
+func TestRemoveClusterKubeConfigs(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cluster := &Cluster{Name: String("mycluster"), KubeConfig: String(testKubeConfig)}
+	merged := filepath.Join(dir, "merged")
+	other := filepath.Join(dir, "other")
+	missing := filepath.Join(dir, "missing")
+
+	for _, path := range []string{merged, other} {
+		_, err = MergeClusterKubeConfig(cluster, path, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = RemoveClusterKubeConfig("mycluster", other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removedFrom, err := RemoveClusterKubeConfigs("mycluster", []string{missing, merged, other, merged})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removedFrom) != 1 || removedFrom[0] != merged {
+		t.Errorf("RemoveClusterKubeConfigs removed from %v, want only %s", removedFrom, merged)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Error("RemoveClusterKubeConfigs created a missing file")
+	}
+
+	config, err := kubeconfig.LoadFile(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Context("mycluster") != nil {
+		t.Error("context is still in the merged file")
+	}
+}
+
+func TestFindClusterKubeConfigs(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cluster := &Cluster{Name: String("mycluster"), KubeConfig: String(testKubeConfig)}
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	missing := filepath.Join(dir, "missing")
+	_, err = MergeClusterKubeConfig(cluster, second, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := os.Getenv("KUBECONFIG")
+	defer os.Setenv("KUBECONFIG", env)
+	os.Setenv("KUBECONFIG", strings.Join([]string{first, second, missing}, string(filepath.ListSeparator)))
+
+	found, err := FindClusterKubeConfigs("mycluster", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0] != second {
+		t.Errorf("FindClusterKubeConfigs = %v, want only %s", found, second)
+	}
+
+	// without paths nothing is removed, not even from $KUBECONFIG
+	removedFrom, err := RemoveClusterKubeConfigs("mycluster", nil)
+	if err != nil || len(removedFrom) != 0 {
+		t.Errorf("RemoveClusterKubeConfigs without paths removed from %v, error %v", removedFrom, err)
+	}
+	config, err := kubeconfig.LoadFile(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Context("mycluster") == nil {
+		t.Error("context was removed from a file which was not given")
+	}
+}
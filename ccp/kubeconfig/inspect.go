@@ -0,0 +1,164 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package kubeconfig
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"time"
+)
+
+// Info describes the API endpoint and certificates of one context in a kubeconfig
+type Info struct {
+	Context string `json:"context"`
+	Server  string `json:"server"`
+	// CABundle is the PEM encoded certificate authority bundle and CACertificates the parsed certificates from it
+	CABundle       []byte              `json:"-"`
+	CACertificates []*x509.Certificate `json:"-"`
+	// ClientCertificate is nil when the user authenticates without a client certificate, e.g. with a token
+	ClientCertificate *x509.Certificate `json:"-"`
+	ClientSubject     string            `json:"client_subject,omitempty"`
+	ClientNotAfter    time.Time         `json:"client_not_after"`
+}
+
+// ExpiresWithin checks if the client certificate expires within d from now. Kubeconfigs without a
+// client certificate never expire
+func (i *Info) ExpiresWithin(d time.Duration) bool {
+	if i.ClientCertificate == nil {
+		return false
+	}
+	return time.Now().Add(d).After(i.ClientNotAfter)
+}
+
+// Expired checks if the client certificate has already expired
+func (i *Info) Expired() bool {
+	return i.ExpiresWithin(0)
+}
+
+// Inspect parses a kubeconfig and describes its current context
+func Inspect(data []byte) (*Info, error) {
+
+	config, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.Inspect()
+}
+
+// InspectInline is Inspect for a kubeconfig from another machine, see ParseInline
+func InspectInline(data []byte) (*Info, error) {
+
+	config, err := ParseInline(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.Inspect()
+}
+
+// Inspect describes the current context of the config, or the first context if no current context is set
+func (c *Config) Inspect() (*Info, error) {
+
+	context := c.currentOrOnlyContext()
+	if context == nil {
+		return nil, errors.New("Kubeconfig has no contexts")
+	}
+
+	info := Info{Context: context.Name}
+
+	cluster := c.Cluster(context.Context.Cluster)
+	if cluster == nil {
+		return nil, errors.New("Kubeconfig context " + context.Name + " uses missing cluster " + context.Context.Cluster)
+	}
+	info.Server = cluster.Cluster.Server
+
+	caBundle, err := dataOrFile(cluster.Cluster.CertificateAuthorityData, cluster.Cluster.CertificateAuthority)
+	if err != nil {
+		return nil, errors.New("Cannot read certificate authority for cluster " + cluster.Name + ": " + err.Error())
+	}
+	if caBundle != nil {
+		info.CABundle = caBundle
+		info.CACertificates, err = parseCertificates(caBundle)
+		if err != nil {
+			return nil, errors.New("Cannot parse certificate authority for cluster " + cluster.Name + ": " + err.Error())
+		}
+	}
+
+	user := c.User(context.Context.User)
+	if user == nil {
+		return nil, errors.New("Kubeconfig context " + context.Name + " uses missing user " + context.Context.User)
+	}
+
+	clientCert, err := dataOrFile(user.User.ClientCertificateData, user.User.ClientCertificate)
+	if err != nil {
+		return nil, errors.New("Cannot read client certificate for user " + user.Name + ": " + err.Error())
+	}
+	if clientCert != nil {
+		certs, err := parseCertificates(clientCert)
+		if err != nil {
+			return nil, errors.New("Cannot parse client certificate for user " + user.Name + ": " + err.Error())
+		}
+		// the first certificate is the client certificate, any others are intermediates
+		info.ClientCertificate = certs[0]
+		info.ClientSubject = certs[0].Subject.String()
+		info.ClientNotAfter = certs[0].NotAfter
+	}
+
+	return &info, nil
+}
+
+// dataOrFile returns the base64 decoded data, or the contents of file when there is no data.
+// nil is returned when neither is set
+func dataOrFile(data string, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return ioutil.ReadFile(file)
+	}
+	return nil, nil
+}
+
+// parseCertificates parses all of the PEM encoded certificates
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificates found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,146 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package kubeconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCertificate returns a PEM encoded self-signed certificate which expires at notAfter
+func testCertificate(t *testing.T, notAfter time.Time) []byte {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kubernetes-admin", Organization: []string{"system:masters"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func testConfig(clientCertificateData, clientCertificate string) []byte {
+	return []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: kubernetes
+  cluster:
+    server: https://10.1.1.10:6443
+users:
+- name: kubernetes-admin
+  user:
+    client-certificate-data: "` + clientCertificateData + `"
+    client-certificate: "` + clientCertificate + `"
+    token: abc
+contexts:
+- name: kubernetes-admin@kubernetes
+  context:
+    cluster: kubernetes
+    user: kubernetes-admin
+current-context: kubernetes-admin@kubernetes
+`)
+}
+
+func TestInspectExpiry(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		notAfter time.Duration
+		within   time.Duration
+		expired  bool
+		expiring bool
+	}{
+		{"valid", 90 * 24 * time.Hour, 30 * 24 * time.Hour, false, false},
+		{"expiring", 10 * 24 * time.Hour, 30 * 24 * time.Hour, false, true},
+		{"expired", -24 * time.Hour, 30 * 24 * time.Hour, true, true},
+	}
+
+	for _, test := range tests {
+		cert := testCertificate(t, time.Now().Add(test.notAfter))
+		info, err := Inspect(testConfig(base64.StdEncoding.EncodeToString(cert), ""))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if info.Server != "https://10.1.1.10:6443" || info.ClientCertificate == nil {
+			t.Fatalf("%s: Inspect = %+v", test.name, info)
+		}
+		if info.Expired() != test.expired || info.ExpiresWithin(test.within) != test.expiring {
+			t.Errorf("%s: Expired() = %v, ExpiresWithin() = %v", test.name, info.Expired(), info.ExpiresWithin(test.within))
+		}
+	}
+
+	info, err := Inspect(testConfig("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ClientCertificate != nil || info.ExpiresWithin(time.Hour) {
+		t.Error("a token kubeconfig has a client certificate which expires")
+	}
+}
+
+func TestInspectInlineIgnoresFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "inspect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "client.crt")
+	err = ioutil.WriteFile(path, testCertificate(t, time.Now().Add(time.Hour)), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Inspect(testConfig("", path))
+	if err != nil || info.ClientCertificate == nil {
+		t.Fatalf("Inspect of a local kubeconfig did not read %s: %v", path, err)
+	}
+
+	info, err = InspectInline(testConfig("", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ClientCertificate != nil {
+		t.Error("InspectInline read a local file named by the kubeconfig")
+	}
+
+	config, err := ParseInline(testConfig("", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.User("kubernetes-admin").User.ClientCertificate != "" {
+		t.Error("ParseInline kept the client certificate path")
+	}
+}
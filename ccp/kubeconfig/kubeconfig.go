@@ -0,0 +1,431 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+// Package kubeconfig reads, merges and writes kubectl config files such as the ones returned by CCP
+package kubeconfig
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Config is a kubectl config file. Fields which are not known are kept in Extra so nothing is lost when
+// a file is read, merged and written back
+type Config struct {
+	APIVersion     string                 `yaml:"apiVersion,omitempty"`
+	Kind           string                 `yaml:"kind,omitempty"`
+	Clusters       []NamedCluster         `yaml:"clusters"`
+	Contexts       []NamedContext         `yaml:"contexts"`
+	CurrentContext string                 `yaml:"current-context"`
+	Preferences    map[string]interface{} `yaml:"preferences"`
+	Users          []NamedUser            `yaml:"users"`
+	Extra          map[string]interface{} `yaml:",inline"`
+}
+
+// NamedCluster is a cluster entry
+type NamedCluster struct {
+	Name    string  `yaml:"name"`
+	Cluster Cluster `yaml:"cluster"`
+}
+
+// Cluster is the API server of a cluster entry
+type Cluster struct {
+	Server                   string                 `yaml:"server"`
+	CertificateAuthority     string                 `yaml:"certificate-authority,omitempty"`
+	CertificateAuthorityData string                 `yaml:"certificate-authority-data,omitempty"`
+	InsecureSkipTLSVerify    bool                   `yaml:"insecure-skip-tls-verify,omitempty"`
+	Extra                    map[string]interface{} `yaml:",inline"`
+}
+
+// NamedUser is a user entry
+type NamedUser struct {
+	Name string `yaml:"name"`
+	User User   `yaml:"user"`
+}
+
+// User is the credentials of a user entry
+type User struct {
+	ClientCertificate     string                 `yaml:"client-certificate,omitempty"`
+	ClientCertificateData string                 `yaml:"client-certificate-data,omitempty"`
+	ClientKey             string                 `yaml:"client-key,omitempty"`
+	ClientKeyData         string                 `yaml:"client-key-data,omitempty"`
+	Token                 string                 `yaml:"token,omitempty"`
+	Username              string                 `yaml:"username,omitempty"`
+	Password              string                 `yaml:"password,omitempty"`
+	Extra                 map[string]interface{} `yaml:",inline"`
+}
+
+// NamedContext is a context entry
+type NamedContext struct {
+	Name    string  `yaml:"name"`
+	Context Context `yaml:"context"`
+}
+
+// Context links a cluster entry and a user entry
+type Context struct {
+	Cluster   string                 `yaml:"cluster"`
+	User      string                 `yaml:"user"`
+	Namespace string                 `yaml:"namespace,omitempty"`
+	Extra     map[string]interface{} `yaml:",inline"`
+}
+
+// Parse parses a kubectl config in YAML or JSON
+func Parse(data []byte) (*Config, error) {
+
+	var config Config
+
+	err := yaml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, errors.New("Cannot parse kubeconfig: " + err.Error())
+	}
+
+	return &config, nil
+}
+
+// ParseInline parses a kubectl config from another machine, such as one fetched from CCP. The certificate
+// authority, client certificate and client key file paths are dropped so that only the inline data is ever
+// used, and a config from a server cannot make the client read local files
+func ParseInline(data []byte) (*Config, error) {
+
+	config, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range config.Clusters {
+		config.Clusters[i].Cluster.CertificateAuthority = ""
+	}
+	for i := range config.Users {
+		config.Users[i].User.ClientCertificate = ""
+		config.Users[i].User.ClientKey = ""
+	}
+
+	return config, nil
+}
+
+// Marshal returns the config as YAML
+func (c *Config) Marshal() ([]byte, error) {
+	if c.APIVersion == "" {
+		c.APIVersion = "v1"
+	}
+	if c.Kind == "" {
+		c.Kind = "Config"
+	}
+
+	// same indentation as kubectl
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err := encoder.Encode(c)
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DefaultPath returns the first file in $KUBECONFIG, or ~/.kube/config
+func DefaultPath() string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)[0]
+	}
+	return homePath()
+}
+
+// SearchPaths returns every file in $KUBECONFIG followed by ~/.kube/config, without repeats. These are all the
+// files DefaultPath may have returned, so a context merged into one of them can be found again
+func SearchPaths() []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, path := range append(filepath.SplitList(os.Getenv("KUBECONFIG")), homePath()) {
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// homePath returns ~/.kube/config
+func homePath() string {
+	home := os.Getenv("HOME")
+	if home == "" {
+		if me, err := user.Current(); err == nil {
+			home = me.HomeDir
+		}
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+// LoadFile reads a kubectl config file. A missing file returns an empty config
+func LoadFile(path string) (*Config, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// WriteFile writes a kubectl config file with owner only permissions. The file is written to a temporary
+// file first and renamed so an existing config is never left half written
+func WriteFile(path string, c *Config) error {
+
+	data, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".kubeconfig-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Context returns the named context or nil
+func (c *Config) Context(name string) *NamedContext {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			return &c.Contexts[i]
+		}
+	}
+	return nil
+}
+
+// Cluster returns the named cluster entry or nil
+func (c *Config) Cluster(name string) *NamedCluster {
+	for i := range c.Clusters {
+		if c.Clusters[i].Name == name {
+			return &c.Clusters[i]
+		}
+	}
+	return nil
+}
+
+// User returns the named user entry or nil
+func (c *Config) User(name string) *NamedUser {
+	for i := range c.Users {
+		if c.Users[i].Name == name {
+			return &c.Users[i]
+		}
+	}
+	return nil
+}
+
+// currentOrOnlyContext returns the current context, or the first one when none is set
+func (c *Config) currentOrOnlyContext() *NamedContext {
+	if context := c.Context(c.CurrentContext); context != nil {
+		return context
+	}
+	if len(c.Contexts) > 0 {
+		return &c.Contexts[0]
+	}
+	return nil
+}
+
+// Rename renames the current context, and the cluster and user entries it uses, to name. The entries which
+// are not used by the current context are dropped, so the config only describes one cluster
+func (c *Config) Rename(name string) error {
+
+	if name == "" {
+		return errors.New("Kubeconfig name is required")
+	}
+
+	context := c.currentOrOnlyContext()
+	if context == nil {
+		return errors.New("Kubeconfig has no contexts")
+	}
+
+	cluster := c.Cluster(context.Context.Cluster)
+	if cluster == nil {
+		return errors.New("Kubeconfig context " + context.Name + " uses missing cluster " + context.Context.Cluster)
+	}
+	user := c.User(context.Context.User)
+	if user == nil {
+		return errors.New("Kubeconfig context " + context.Name + " uses missing user " + context.Context.User)
+	}
+
+	renamedContext := *context
+	renamedCluster := *cluster
+	renamedUser := *user
+
+	renamedContext.Name = name
+	renamedContext.Context.Cluster = name
+	renamedContext.Context.User = name
+	renamedCluster.Name = name
+	renamedUser.Name = name
+
+	c.Contexts = []NamedContext{renamedContext}
+	c.Clusters = []NamedCluster{renamedCluster}
+	c.Users = []NamedUser{renamedUser}
+	c.CurrentContext = name
+
+	return nil
+}
+
+// Merge adds the clusters, users and contexts of other to c. Entries with the same name are replaced,
+// everything else in c is kept. The current context of c is not changed
+func (c *Config) Merge(other *Config) {
+
+	for _, cluster := range other.Clusters {
+		if existing := c.Cluster(cluster.Name); existing != nil {
+			*existing = cluster
+		} else {
+			c.Clusters = append(c.Clusters, cluster)
+		}
+	}
+	for _, user := range other.Users {
+		if existing := c.User(user.Name); existing != nil {
+			*existing = user
+		} else {
+			c.Users = append(c.Users, user)
+		}
+	}
+	for _, context := range other.Contexts {
+		if existing := c.Context(context.Name); existing != nil {
+			*existing = context
+		} else {
+			c.Contexts = append(c.Contexts, context)
+		}
+	}
+}
+
+// Remove removes the named context, and the cluster and user entries it uses when no other context
+// uses them. It returns false if there was no such context
+func (c *Config) Remove(name string) bool {
+
+	context := c.Context(name)
+	if context == nil {
+		return false
+	}
+	clusterName := context.Context.Cluster
+	userName := context.Context.User
+
+	var contexts []NamedContext
+	clusterUsed, userUsed := false, false
+	for _, ctx := range c.Contexts {
+		if ctx.Name == name {
+			continue
+		}
+		contexts = append(contexts, ctx)
+		clusterUsed = clusterUsed || ctx.Context.Cluster == clusterName
+		userUsed = userUsed || ctx.Context.User == userName
+	}
+	c.Contexts = contexts
+
+	if !clusterUsed {
+		var clusters []NamedCluster
+		for _, cluster := range c.Clusters {
+			if cluster.Name != clusterName {
+				clusters = append(clusters, cluster)
+			}
+		}
+		c.Clusters = clusters
+	}
+	if !userUsed {
+		var users []NamedUser
+		for _, user := range c.Users {
+			if user.Name != userName {
+				users = append(users, user)
+			}
+		}
+		c.Users = users
+	}
+
+	if c.CurrentContext == name {
+		c.CurrentContext = ""
+	}
+
+	return true
+}
+
+// UseContext sets the current context
+func (c *Config) UseContext(name string) error {
+	if c.Context(name) == nil {
+		return errors.New("Kubeconfig has no context named " + name)
+	}
+	c.CurrentContext = name
+	return nil
+}
+
+// MergeIntoFile merges config into the kubectl config file at path, creating it if needed.
+// When setContext is true the current context of the file is set to the current context of config
+func MergeIntoFile(path string, config *Config, setContext bool) error {
+
+	existing, err := LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	existing.Merge(config)
+
+	if setContext {
+		err = existing.UseContext(config.CurrentContext)
+		if err != nil {
+			return err
+		}
+	}
+
+	return WriteFile(path, existing)
+}
+
+// RemoveFromFile removes the named context from the kubectl config file at path. It returns false
+// without writing anything if the file has no such context
+func RemoveFromFile(path string, name string) (bool, error) {
+
+	existing, err := LoadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	if !existing.Remove(name) {
+		return false, nil
+	}
+
+	return true, WriteFile(path, existing)
+}
@@ -0,0 +1,153 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package kubeconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const ccpKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: kubernetes
+  cluster:
+    server: https://10.1.1.10:6443
+    certificate-authority-data: Q0E=
+users:
+- name: kubernetes-admin
+  user:
+    client-certificate-data: Q0VSVA==
+    client-key-data: S0VZ
+contexts:
+- name: kubernetes-admin@kubernetes
+  context:
+    cluster: kubernetes
+    user: kubernetes-admin
+current-context: kubernetes-admin@kubernetes
+`
+
+const existingKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: other
+  cluster:
+    server: https://other:6443
+users:
+- name: other
+  user:
+    token: abc
+contexts:
+- name: other
+  context:
+    cluster: other
+    user: other
+current-context: other
+preferences: {}
+colors: true
+`
+
+func TestMergeAndRemove(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	err = ioutil.WriteFile(path, []byte(existingKubeConfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := Parse([]byte(ccpKubeConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = config.Rename("mycluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = MergeIntoFile(path, config, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Contexts) != 2 || merged.CurrentContext != "mycluster" {
+		t.Errorf("merged contexts %v, current %q", merged.Contexts, merged.CurrentContext)
+	}
+	if merged.Cluster("mycluster") == nil || merged.User("mycluster") == nil {
+		t.Error("merged file is missing the mycluster cluster or user")
+	}
+	if merged.Extra["colors"] != true {
+		t.Error("merge lost a field it does not know")
+	}
+
+	removed, err := RemoveFromFile(path, "mycluster")
+	if err != nil || !removed {
+		t.Fatalf("RemoveFromFile = %v, %v", removed, err)
+	}
+	left, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left.Contexts) != 1 || len(left.Clusters) != 1 || len(left.Users) != 1 || left.Context("other") == nil {
+		t.Errorf("after remove %+v", left)
+	}
+
+	removed, err = RemoveFromFile(path, "mycluster")
+	if err != nil || removed {
+		t.Errorf("second RemoveFromFile = %v, %v", removed, err)
+	}
+}
+
+func TestSearchPaths(t *testing.T) {
+
+	env, home := os.Getenv("KUBECONFIG"), os.Getenv("HOME")
+	defer func() {
+		os.Setenv("KUBECONFIG", env)
+		os.Setenv("HOME", home)
+	}()
+	os.Setenv("HOME", "/home/me")
+	defaultPath := filepath.Join("/home/me", ".kube", "config")
+
+	tests := []struct {
+		env         string
+		defaultPath string
+		paths       []string
+	}{
+		{"", defaultPath, []string{defaultPath}},
+		{"/a" + string(filepath.ListSeparator) + "/b", "/a", []string{"/a", "/b", defaultPath}},
+		{defaultPath + string(filepath.ListSeparator) + "/a", defaultPath, []string{defaultPath, "/a"}},
+	}
+
+	for _, test := range tests {
+		os.Setenv("KUBECONFIG", test.env)
+		if got := DefaultPath(); got != test.defaultPath {
+			t.Errorf("KUBECONFIG=%s DefaultPath() = %s, want %s", test.env, got, test.defaultPath)
+		}
+		if got := SearchPaths(); strings.Join(got, ",") != strings.Join(test.paths, ",") {
+			t.Errorf("KUBECONFIG=%s SearchPaths() = %v, want %v", test.env, got, test.paths)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package kubeconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPClient returns an HTTP client which authenticates to the API server of the current context with
+// the certificates or token from the config, and the server URL to use it with
+func (c *Config) HTTPClient() (*http.Client, string, error) {
+
+	context := c.currentOrOnlyContext()
+	if context == nil {
+		return nil, "", errors.New("Kubeconfig has no contexts")
+	}
+	cluster := c.Cluster(context.Context.Cluster)
+	if cluster == nil {
+		return nil, "", errors.New("Kubeconfig context " + context.Name + " uses missing cluster " + context.Context.Cluster)
+	}
+	user := c.User(context.Context.User)
+	if user == nil {
+		return nil, "", errors.New("Kubeconfig context " + context.Name + " uses missing user " + context.Context.User)
+	}
+	if cluster.Cluster.Server == "" {
+		return nil, "", errors.New("Kubeconfig cluster " + cluster.Name + " has no server")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cluster.Cluster.InsecureSkipTLSVerify}
+
+	caBundle, err := dataOrFile(cluster.Cluster.CertificateAuthorityData, cluster.Cluster.CertificateAuthority)
+	if err != nil {
+		return nil, "", errors.New("Cannot read certificate authority for cluster " + cluster.Name + ": " + err.Error())
+	}
+	if caBundle != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, "", errors.New("Cannot parse certificate authority for cluster " + cluster.Name)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	clientCert, err := dataOrFile(user.User.ClientCertificateData, user.User.ClientCertificate)
+	if err != nil {
+		return nil, "", errors.New("Cannot read client certificate for user " + user.Name + ": " + err.Error())
+	}
+	clientKey, err := dataOrFile(user.User.ClientKeyData, user.User.ClientKey)
+	if err != nil {
+		return nil, "", errors.New("Cannot read client key for user " + user.Name + ": " + err.Error())
+	}
+	if clientCert != nil && clientKey != nil {
+		keyPair, err := tls.X509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, "", errors.New("Cannot load client certificate for user " + user.Name + ": " + err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{keyPair}
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}
+	if user.User.Token != "" {
+		transport = &bearerTransport{token: user.User.Token, next: transport}
+	} else if user.User.Username != "" {
+		transport = &basicAuthTransport{username: user.User.Username, password: user.User.Password, next: transport}
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}
+
+	return client, strings.TrimSuffix(cluster.Cluster.Server, "/"), nil
+}
+
+type bearerTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+type basicAuthTransport struct {
+	username string
+	password string
+	next     http.RoundTripper
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(t.username, t.password)
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,128 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package kubeconfig
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// transportConfig is a config for server with user
+func transportConfig(server, caData string, insecure bool, user User) *Config {
+	return &Config{
+		Clusters: []NamedCluster{{Name: "kubernetes", Cluster: Cluster{
+			Server:                   server,
+			CertificateAuthorityData: caData,
+			InsecureSkipTLSVerify:    insecure,
+		}}},
+		Users:    []NamedUser{{Name: "admin", User: user}},
+		Contexts: []NamedContext{{Name: "admin@kubernetes", Context: Context{Cluster: "kubernetes", User: "admin"}}},
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+	caData := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	tests := []struct {
+		name     string
+		caData   string
+		insecure bool
+		user     User
+		want     string
+		err      string
+	}{
+		{"token", caData, false, User{Token: "abc"}, "Bearer abc", ""},
+		{"basic auth", caData, false, User{Username: "admin", Password: "secret"}, "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:secret")), ""},
+		{"token first", caData, false, User{Token: "abc", Username: "admin"}, "Bearer abc", ""},
+		{"insecure", "", true, User{Token: "abc"}, "Bearer abc", ""},
+		{"unknown CA", "", false, User{Token: "abc"}, "", "certificate"},
+	}
+
+	for _, test := range tests {
+		config := transportConfig(server.URL+"/", test.caData, test.insecure, test.user)
+		client, serverURL, err := config.HTTPClient()
+		if err != nil {
+			t.Errorf("%s: HTTPClient error %v", test.name, err)
+			continue
+		}
+		if serverURL != server.URL {
+			t.Errorf("%s: server %s, want %s", test.name, serverURL, server.URL)
+		}
+
+		resp, err := client.Get(serverURL + "/readyz")
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error %v", test.name, err)
+			continue
+		}
+		var body strings.Builder
+		buf := make([]byte, 512)
+		n, _ := resp.Body.Read(buf)
+		body.Write(buf[:n])
+		resp.Body.Close()
+		if body.String() != test.want {
+			t.Errorf("%s: Authorization %q, want %q", test.name, body.String(), test.want)
+		}
+	}
+}
+
+func TestHTTPClientErrors(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		config *Config
+		err    string
+	}{
+		{"no contexts", &Config{}, "has no contexts"},
+		{"missing cluster", func() *Config {
+			c := transportConfig("https://10.1.1.10:6443", "", false, User{})
+			c.Clusters = nil
+			return c
+		}(), "missing cluster"},
+		{"missing user", func() *Config {
+			c := transportConfig("https://10.1.1.10:6443", "", false, User{})
+			c.Users = nil
+			return c
+		}(), "missing user"},
+		{"no server", transportConfig("", "", false, User{}), "has no server"},
+		{"bad CA", transportConfig("https://10.1.1.10:6443", base64.StdEncoding.EncodeToString([]byte("not a certificate")), false, User{}), "Cannot parse certificate authority"},
+		{"bad client certificate", transportConfig("https://10.1.1.10:6443", "", false, User{
+			ClientCertificateData: base64.StdEncoding.EncodeToString([]byte("cert")),
+			ClientKeyData:         base64.StdEncoding.EncodeToString([]byte("key")),
+		}), "Cannot load client certificate"},
+	}
+
+	for _, test := range tests {
+		_, _, err := test.config.HTTPClient()
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
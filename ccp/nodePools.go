@@ -0,0 +1,387 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	validator "gopkg.in/validator.v2"
+)
+
+// ListNodePools returns all of the worker node pools for a cluster
+func (s *Client) ListNodePools(clusterUUID string) ([]WorkerNodePool, error) {
+	Debug(1, "Entered ListNodePools for UUID "+clusterUUID)
+
+	if clusterUUID == "" {
+		return nil, errors.New("Cluster UUID is required")
+	}
+
+	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/node-pools/"
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := s.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	Debug(3, "Node pools JSON Payload:\n"+string(bytes))
+
+	var data []WorkerNodePool
+
+	err = json.Unmarshal(bytes, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	Debug(2, "Found "+strconv.Itoa(len(data))+" node pools")
+	return data, nil
+}
+
+// GetNodePool returns the named worker node pool for a cluster
+func (s *Client) GetNodePool(clusterUUID, poolName string) (*WorkerNodePool, error) {
+	Debug(1, "Entered GetNodePool "+poolName+" for UUID "+clusterUUID)
+
+	if clusterUUID == "" {
+		return nil, errors.New("Cluster UUID is required")
+	}
+	if poolName == "" {
+		return nil, errors.New("Node pool name is required")
+	}
+
+	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/node-pools/" + poolName + "/"
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := s.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var data WorkerNodePool
+
+	err = json.Unmarshal(bytes, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// AddNodePool adds a new worker node pool to an existing cluster. The pool passed in is not changed
+func (s *Client) AddNodePool(clusterUUID string, newPool *WorkerNodePool) (*WorkerNodePool, error) {
+
+	if clusterUUID == "" {
+		return nil, errors.New("Cluster UUID is required")
+	}
+	if newPool == nil {
+		return nil, errors.New("Node pool is required")
+	}
+
+	Debug(1, "Entered AddNodePool for UUID "+clusterUUID)
+
+	// work on a copy so the fields cleared below stay as the caller set them
+	pool := *newPool
+
+	errs := validator.Validate(&pool)
+	if errs != nil {
+		Debug(1, "Errors validating WorkerNodePool struct with validator.Validate(): "+errs.Error())
+		return nil, errs
+	}
+
+	// same as AddCluster, an empty Nodes slice is sent as null which CCP doesn't like
+	if pool.Nodes != nil && len(*pool.Nodes) == 0 {
+		pool.Nodes = nil
+	}
+	if pool.GPUs != nil && len(*pool.GPUs) == 0 {
+		pool.GPUs = nil
+	}
+
+	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/node-pools/"
+
+	j, err := json.Marshal(pool)
+	if err != nil {
+		return nil, err
+	}
+	Debug(3, "Sending JSON: "+string(j))
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(j))
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := s.doRequest(req)
+	if err != nil {
+		Debug(1, "Errors POSTing with s.doRequest: "+err.Error())
+		return nil, err
+	}
+
+	var data WorkerNodePool
+
+	err = json.Unmarshal(bytes, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// ResizeNodePool changes the size of the named worker node pool with ScaleCluster and returns the pool
+func (s *Client) ResizeNodePool(clusterUUID, poolName string, size int64) (*WorkerNodePool, error) {
+	Debug(1, "Entered ResizeNodePool "+poolName+" for UUID "+clusterUUID)
+
+	if clusterUUID == "" {
+		return nil, errors.New("Cluster UUID is required")
+	}
+	if poolName == "" {
+		return nil, errors.New("Node pool name is required")
+	}
+	if size < 1 {
+		return nil, errors.New("Node pool size must be at least 1")
+	}
+
+	_, err := s.ScaleCluster(clusterUUID, poolName, int(size))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetNodePool(clusterUUID, poolName)
+}
+
+// DeleteNodePool deletes the named worker node pool from a cluster
+func (s *Client) DeleteNodePool(clusterUUID, poolName string) error {
+	Debug(1, "Entered DeleteNodePool "+poolName+" for UUID "+clusterUUID)
+
+	if clusterUUID == "" {
+		return errors.New("Cluster UUID is required")
+	}
+	if poolName == "" {
+		return errors.New("Node pool name is required")
+	}
+
+	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/node-pools/" + poolName + "/"
+
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.doRequest(req)
+	if err != nil {
+		return err
+	}
+
+	Debug(2, "Request sent to API with success response")
+	return nil
+}
+
+// DiscoverNodePool returns the name of the only worker node pool of a cluster. If the cluster has
+// more than one pool an error listing the pool names is returned, so the caller must choose one
+func (s *Client) DiscoverNodePool(clusterUUID string) (string, error) {
+
+	pools, err := s.ListNodePools(clusterUUID)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(pools) {
+	case 0:
+		return "", errors.New("Cluster " + clusterUUID + " has no worker node pools")
+	case 1:
+		if pools[0].Name == nil {
+			return "", errors.New("Worker node pool for cluster " + clusterUUID + " has no name")
+		}
+		return *pools[0].Name, nil
+	}
+
+	var names []string
+	for _, pool := range pools {
+		if pool.Name != nil {
+			names = append(names, *pool.Name)
+		}
+	}
+	return "", errors.New("Cluster " + clusterUUID + " has more than one worker node pool, choose one of: " + strings.Join(names, ", "))
+}
+
+// NodePoolError is returned when one or more nodes in a pool failed to provision
+type NodePoolError struct {
+	Pool  string
+	Nodes []Node
+}
+
+func (e *NodePoolError) Error() string {
+	var reasons []string
+	for _, node := range e.Nodes {
+		reasons = append(reasons, nodeName(node)+": "+nodeReason(node))
+	}
+	return "Node pool " + e.Pool + " has failed nodes: " + strings.Join(reasons, "; ")
+}
+
+// IsNodeReady checks if the node phase, or status when no phase is reported, is ready
+func IsNodeReady(node Node) bool {
+	state := node.Phase
+	if state == nil || *state == "" {
+		state = node.Status
+	}
+	if state == nil {
+		return false
+	}
+	switch strings.ToUpper(*state) {
+	case "READY", "RUNNING":
+		return true
+	}
+	return false
+}
+
+// IsNodeFailed checks if the node phase or status reports a failure
+func IsNodeFailed(node Node) bool {
+	for _, state := range []*string{node.Phase, node.Status} {
+		if state == nil {
+			continue
+		}
+		switch strings.ToUpper(*state) {
+		case "ERROR", "FAILED", "FAILURE":
+			return true
+		}
+	}
+	return false
+}
+
+// countNodes returns the number of nodes, how many are ready and the nodes which have failed
+func countNodes(nodes *[]Node) (count int, ready int, failed []Node) {
+	if nodes == nil {
+		return 0, 0, nil
+	}
+	for _, node := range *nodes {
+		if IsNodeFailed(node) {
+			failed = append(failed, node)
+		} else if IsNodeReady(node) {
+			ready++
+		}
+	}
+	return len(*nodes), ready, failed
+}
+
+func nodeName(node Node) string {
+	if node.Name != nil {
+		return *node.Name
+	}
+	return "<unnamed>"
+}
+
+// nodeReason builds the most useful failure description from the node status fields
+func nodeReason(node Node) string {
+	var parts []string
+	for _, field := range []*string{node.Status, node.Phase, node.StatusReason, node.StatusDetail} {
+		if field != nil && *field != "" {
+			parts = append(parts, *field)
+		}
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, " - ")
+}
+
+// ScaleClusterAndWait scales a worker node pool and waits until the pool has size nodes which are all ready,
+// see WaitForNodePool
+func (s *Client) ScaleClusterAndWait(ctx context.Context, clusterUUID, workerPoolName string, size int) (*WorkerNodePool, error) {
+	Debug(1, "Entered ScaleClusterAndWait for UUID "+clusterUUID)
+
+	_, err := s.ScaleCluster(clusterUUID, workerPoolName, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.WaitForNodePool(ctx, clusterUUID, workerPoolName, int64(size))
+}
+
+// WaitForNodePool waits until the named worker node pool has size nodes which are all ready. A *NodePoolError is
+// returned as soon as a node in the pool fails. Nodes which had already failed at the first check are left out:
+// they do not fail the wait and are not waited for, so a pool with a broken node can still be scaled
+func (s *Client) WaitForNodePool(ctx context.Context, clusterUUID, workerPoolName string, size int64) (*WorkerNodePool, error) {
+
+	var pool *WorkerNodePool
+	var failedBefore map[string]bool
+	lastState := "not checked"
+
+	err := poll(ctx, ClusterPollInterval, func() (bool, error) {
+		cluster, err := s.GetClusterByUUID(clusterUUID)
+		if err != nil {
+			return false, err
+		}
+
+		pool = findWorkerNodePool(cluster, workerPoolName)
+		if pool == nil {
+			return false, errors.New("Worker node pool " + workerPoolName + " not found in cluster " + clusterUUID)
+		}
+
+		count, ready, failed := countNodes(pool.Nodes)
+		if failedBefore == nil {
+			failedBefore = map[string]bool{}
+			for _, node := range failed {
+				failedBefore[nodeName(node)] = true
+			}
+		}
+		var newlyFailed []Node
+		for _, node := range failed {
+			if !failedBefore[nodeName(node)] {
+				newlyFailed = append(newlyFailed, node)
+			}
+		}
+		if len(newlyFailed) > 0 {
+			return false, &NodePoolError{Pool: workerPoolName, Nodes: newlyFailed}
+		}
+
+		lastState = fmt.Sprintf("%d nodes, %d ready, want %d", count, ready, size)
+		if len(failed) > 0 {
+			lastState += fmt.Sprintf(", %d already failed", len(failed))
+		}
+		Debug(2, "Node pool "+workerPoolName+": "+lastState)
+
+		return int64(count) == size && int64(ready+len(failed)) == size, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("waiting for node pool %s (%s): %w", workerPoolName, lastState, err)
+		}
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// findWorkerNodePool returns the named worker node pool from a cluster or nil if it does not exist
+func findWorkerNodePool(cluster *Cluster, poolName string) *WorkerNodePool {
+	if cluster == nil || cluster.WorkerNodePool == nil {
+		return nil
+	}
+	for i, pool := range *cluster.WorkerNodePool {
+		if pool.Name != nil && *pool.Name == poolName {
+			return &(*cluster.WorkerNodePool)[i]
+		}
+	}
+	return nil
+}
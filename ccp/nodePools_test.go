@@ -0,0 +1,229 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddNodePoolDoesNotChangePool(t *testing.T) {
+
+	var sent map[string]interface{}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &sent)
+		w.Write(body)
+	}))
+
+	nodes := []Node{}
+	gpus := []string{}
+	pool := &WorkerNodePool{
+		Name:     String("gpu"),
+		Size:     Int64(2),
+		Template: String("ccp-tenant-image"),
+		VCPUs:    Int64(8),
+		Memory:   Int64(32768),
+		Nodes:    &nodes,
+		GPUs:     &gpus,
+	}
+
+	_, err := client.AddNodePool("uuid", pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.Nodes != &nodes || pool.GPUs != &gpus {
+		t.Error("AddNodePool changed the pool passed in")
+	}
+	if _, ok := sent["nodes"]; ok {
+		t.Error("AddNodePool sent an empty nodes list")
+	}
+	if _, ok := sent["gpus"]; ok {
+		t.Error("AddNodePool sent an empty gpus list")
+	}
+}
+
+func TestResizeNodePool(t *testing.T) {
+
+	var patched string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/clusters/uuid/node-pools/workers/" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case "PATCH":
+			body, _ := ioutil.ReadAll(r.Body)
+			patched = string(body)
+			w.Write(body)
+		case "GET":
+			w.Write([]byte(`{"name": "workers", "size": 3}`))
+		}
+	}))
+
+	pool, err := client.ResizeNodePool("uuid", "workers", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(patched, `"size":3`) {
+		t.Errorf("ResizeNodePool sent %s", patched)
+	}
+	if pool.Size == nil || *pool.Size != 3 {
+		t.Errorf("ResizeNodePool returned %+v", pool)
+	}
+
+	for _, size := range []int64{0, -1} {
+		_, err = client.ResizeNodePool("uuid", "workers", size)
+		if err == nil {
+			t.Errorf("ResizeNodePool accepted size %d", size)
+		}
+	}
+}
+
+func TestDiscoverNodePool(t *testing.T) {
+
+	tests := []struct {
+		pools   string
+		want    string
+		wantErr string
+	}{
+		{`[{"name": "workers"}]`, "workers", ""},
+		{`[]`, "", "has no worker node pools"},
+		{`[{"name": "a"}, {"name": "b"}]`, "", "choose one of: a, b"},
+	}
+
+	for _, test := range tests {
+		pools := test.pools
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(pools))
+		}))
+
+		got, err := client.DiscoverNodePool("uuid")
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("DiscoverNodePool(%s) error = %v, want %q", test.pools, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("DiscoverNodePool(%s) = %q, %v, want %q", test.pools, got, err, test.want)
+		}
+	}
+}
+
+func TestCountNodes(t *testing.T) {
+
+	node := func(status, phase string) Node {
+		return Node{Name: String("n"), Status: String(status), Phase: String(phase)}
+	}
+
+	tests := []struct {
+		nodes  []Node
+		count  int
+		ready  int
+		failed int
+	}{
+		{nil, 0, 0, 0},
+		{[]Node{node("READY", ""), node("CREATING", "")}, 2, 1, 0},
+		{[]Node{node("READY", "Running"), node("", "Ready")}, 2, 2, 0},
+		{[]Node{node("ERROR", ""), node("READY", "Failed"), node("READY", "")}, 3, 1, 2},
+	}
+
+	for i, test := range tests {
+		nodes := &test.nodes
+		if test.nodes == nil {
+			nodes = nil
+		}
+		count, ready, failed := countNodes(nodes)
+		if count != test.count || ready != test.ready || len(failed) != test.failed {
+			t.Errorf("%d: countNodes = %d, %d, %d failed, want %d, %d, %d failed", i, count, ready, len(failed), test.count, test.ready, test.failed)
+		}
+	}
+}
+
+// poolServer serves a cluster whose workers pool has the node states in polls, one entry per GET, repeating the last
+func poolServer(t *testing.T, polls [][]string) *Client {
+	calls := 0
+	return newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		states := polls[len(polls)-1]
+		if calls < len(polls) {
+			states = polls[calls]
+		}
+		calls++
+
+		var nodes []Node
+		for i, state := range states {
+			nodes = append(nodes, Node{Name: String("worker-" + string(rune('a'+i))), Status: String(state)})
+		}
+		json.NewEncoder(w).Encode(Cluster{
+			UUID:           String("uuid"),
+			WorkerNodePool: &[]WorkerNodePool{{Name: String("workers"), Nodes: &nodes}},
+		})
+	}))
+}
+
+func TestWaitForNodePool(t *testing.T) {
+
+	interval := ClusterPollInterval
+	ClusterPollInterval = time.Millisecond
+	defer func() { ClusterPollInterval = interval }()
+
+	client := poolServer(t, [][]string{
+		{"READY"},
+		{"READY", "CREATING"},
+		{"READY", "READY"},
+	})
+	pool, err := client.WaitForNodePool(context.Background(), "uuid", "workers", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*pool.Nodes) != 2 {
+		t.Errorf("WaitForNodePool returned %d nodes, want 2", len(*pool.Nodes))
+	}
+
+	client = poolServer(t, [][]string{{"READY", "CREATING"}, {"READY", "ERROR"}})
+	_, err = client.WaitForNodePool(context.Background(), "uuid", "workers", 2)
+	var poolErr *NodePoolError
+	if !errors.As(err, &poolErr) || len(poolErr.Nodes) != 1 {
+		t.Errorf("WaitForNodePool with a failed node returned %v", err)
+	}
+
+	// a node which had already failed neither fails the wait nor is waited for, a node which fails later does
+	client = poolServer(t, [][]string{{"ERROR"}, {"ERROR", "CREATING"}, {"ERROR", "READY"}})
+	_, err = client.WaitForNodePool(context.Background(), "uuid", "workers", 2)
+	if err != nil {
+		t.Errorf("WaitForNodePool with a node failed before the wait returned %v", err)
+	}
+	client = poolServer(t, [][]string{{"ERROR", "CREATING"}, {"ERROR", "ERROR"}})
+	_, err = client.WaitForNodePool(context.Background(), "uuid", "workers", 2)
+	if !errors.As(err, &poolErr) || len(poolErr.Nodes) != 1 || *poolErr.Nodes[0].Name != "worker-b" {
+		t.Errorf("WaitForNodePool with a node failing during the wait returned %v", err)
+	}
+
+	client = poolServer(t, [][]string{{"READY"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = client.WaitForNodePool(ctx, "uuid", "workers", 2)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "1 nodes, 1 ready, want 2") {
+		t.Errorf("WaitForNodePool timeout returned %v", err)
+	}
+}
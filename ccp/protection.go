@@ -0,0 +1,186 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProtectedMarker is stored in the cluster Description to mark a cluster as protected from deletion
+const ProtectedMarker = "[ccp:protected]"
+
+// ErrClusterProtected is returned when deleting a cluster that is protected
+var ErrClusterProtected = errors.New("Cluster is protected from deletion")
+
+// ProtectionPolicy is a deletion policy, usually kept in a local file, see LoadProtectionPolicy. Clusters whose
+// name matches a Protected pattern are refused by DeleteCluster unless the name also matches an Allowed pattern.
+// Patterns use path.Match syntax, e.g. "prod-*". A cluster with ProtectedMarker in its Description is protected
+// whatever the policy says
+type ProtectionPolicy struct {
+	Protected []string `json:"protected" yaml:"protected"`
+	Allowed   []string `json:"allowed" yaml:"allowed"`
+}
+
+// SetProtectedClusters sets the list of cluster name patterns (path.Match syntax, e.g. "prod-*") which
+// DeleteCluster will refuse to delete
+func (s *Client) SetProtectedClusters(patterns []string) error {
+	err := checkClusterPatterns(patterns)
+	if err != nil {
+		return err
+	}
+	s.ProtectedClusters = patterns
+	return nil
+}
+
+// SetProtectionPolicy sets both the protected and the allowed cluster name patterns on the Client
+func (s *Client) SetProtectionPolicy(policy ProtectionPolicy) error {
+	err := checkClusterPatterns(policy.Protected)
+	if err != nil {
+		return err
+	}
+	err = checkClusterPatterns(policy.Allowed)
+	if err != nil {
+		return err
+	}
+	s.ProtectedClusters = policy.Protected
+	s.AllowedClusters = policy.Allowed
+	return nil
+}
+
+// LoadProtectionPolicy reads a policy file, YAML or JSON, e.g.
+//
+//	protected: ["prod-*", "db-*"]
+//	allowed: ["prod-scratch-*"]
+func LoadProtectionPolicy(policyFile string) (*ProtectionPolicy, error) {
+	Debug(1, "Entered LoadProtectionPolicy for "+policyFile)
+
+	data, err := ioutil.ReadFile(policyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &ProtectionPolicy{}
+	// YAML is a superset of JSON so one decoder reads both
+	err = yaml.Unmarshal(data, policy)
+	if err != nil {
+		return nil, errors.New("Cannot read protection policy " + policyFile + ": " + err.Error())
+	}
+	err = checkClusterPatterns(policy.Protected)
+	if err == nil {
+		err = checkClusterPatterns(policy.Allowed)
+	}
+	if err != nil {
+		return nil, errors.New("Protection policy " + policyFile + ": " + err.Error())
+	}
+
+	return policy, nil
+}
+
+// checkClusterPatterns checks the patterns are valid before they are stored
+func checkClusterPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.New("Invalid cluster pattern '" + pattern + "': " + err.Error())
+		}
+	}
+	return nil
+}
+
+// matchClusterName reports if name matches any of the patterns
+func matchClusterName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, name)
+		if err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClusterProtected checks if a cluster has the protected marker in its Description, or
+// its name matches one of the protected cluster patterns on the Client and none of the allowed ones
+func (s *Client) IsClusterProtected(cluster *Cluster) bool {
+
+	if cluster == nil {
+		return false
+	}
+
+	if cluster.Description != nil && strings.Contains(*cluster.Description, ProtectedMarker) {
+		return true
+	}
+
+	if cluster.Name != nil && matchClusterName(s.ProtectedClusters, *cluster.Name) {
+		return !matchClusterName(s.AllowedClusters, *cluster.Name)
+	}
+
+	return false
+}
+
+// ProtectCluster adds the protected marker to the cluster Description
+func (s *Client) ProtectCluster(clusterUUID string) (*Cluster, error) {
+	Debug(1, "Entered ProtectCluster for UUID "+clusterUUID)
+
+	cluster, err := s.GetClusterByUUID(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	description := ""
+	if cluster.Description != nil {
+		description = *cluster.Description
+	}
+
+	if strings.Contains(description, ProtectedMarker) {
+		Debug(2, "Cluster "+clusterUUID+" is already protected")
+		return cluster, nil
+	}
+
+	description = strings.TrimSpace(description + " " + ProtectedMarker)
+
+	return s.PatchCluster(&Cluster{Description: &description}, clusterUUID)
+}
+
+// UnprotectCluster removes the protected marker from the cluster Description
+func (s *Client) UnprotectCluster(clusterUUID string) (*Cluster, error) {
+	Debug(1, "Entered UnprotectCluster for UUID "+clusterUUID)
+
+	cluster, err := s.GetClusterByUUID(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cluster.Description == nil || !strings.Contains(*cluster.Description, ProtectedMarker) {
+		Debug(2, "Cluster "+clusterUUID+" is not protected")
+		return cluster, nil
+	}
+
+	// an empty string is still sent so that CCP clears the description
+	description := strings.TrimSpace(strings.Replace(*cluster.Description, ProtectedMarker, "", -1))
+
+	return s.PatchCluster(&Cluster{Description: &description}, clusterUUID)
+}
+
+// DeleteProtectedCluster deletes a cluster even if it is protected. This is the explicit override for DeleteCluster
+func (s *Client) DeleteProtectedCluster(clusterUUID string) error {
+	Debug(1, "Entered DeleteProtectedCluster for UUID "+clusterUUID)
+
+	return s.deleteCluster(clusterUUID)
+}
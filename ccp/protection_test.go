@@ -0,0 +1,120 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsClusterProtected(t *testing.T) {
+
+	policy := ProtectionPolicy{
+		Protected: []string{"prod-*", "db-*"},
+		Allowed:   []string{"prod-scratch-*"},
+	}
+
+	tests := []struct {
+		name        string
+		description string
+		want        bool
+	}{
+		{"dev-1", "", false},
+		{"prod-1", "", true},
+		{"db-main", "", true},
+		{"prod-scratch-1", "", false},
+		{"dev-1", "keep " + ProtectedMarker, true},
+		{"prod-scratch-1", ProtectedMarker, true},
+	}
+
+	client := &Client{}
+	err := client.SetProtectionPolicy(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		name := test.name
+		description := test.description
+		got := client.IsClusterProtected(&Cluster{Name: &name, Description: &description})
+		if got != test.want {
+			t.Errorf("IsClusterProtected(%q, %q) = %v, want %v", test.name, test.description, got, test.want)
+		}
+	}
+
+	if client.IsClusterProtected(nil) {
+		t.Error("IsClusterProtected(nil) = true, want false")
+	}
+}
+
+func TestSetProtectionPolicyInvalidPattern(t *testing.T) {
+
+	client := &Client{}
+	err := client.SetProtectionPolicy(ProtectionPolicy{Allowed: []string{"prod-["}})
+	if err == nil {
+		t.Fatal("SetProtectionPolicy accepted an invalid pattern")
+	}
+	if client.ProtectedClusters != nil || client.AllowedClusters != nil {
+		t.Error("SetProtectionPolicy changed the client after an error")
+	}
+}
+
+func TestLoadProtectionPolicy(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		file      string
+		data      string
+		protected int
+		allowed   int
+		wantErr   bool
+	}{
+		{"policy.yaml", "protected: [\"prod-*\", \"db-*\"]\nallowed:\n  - prod-scratch-*\n", 2, 1, false},
+		{"policy.json", `{"protected": ["prod-*"]}`, 1, 0, false},
+		{"bad.yaml", "protected: [\"prod-[\"]\n", 0, 0, true},
+		{"notyaml.yaml", "protected: [", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, test.file)
+		err = ioutil.WriteFile(path, []byte(test.data), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		policy, err := LoadProtectionPolicy(path)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("LoadProtectionPolicy(%s) did not fail", test.file)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("LoadProtectionPolicy(%s): %v", test.file, err)
+			continue
+		}
+		if len(policy.Protected) != test.protected || len(policy.Allowed) != test.allowed {
+			t.Errorf("LoadProtectionPolicy(%s) = %+v", test.file, policy)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SpecValues are the values for ${name} variables in a cluster spec
+type SpecValues map[string]string
+
+// SpecOptions control how cluster spec files are read
+type SpecOptions struct {
+	Strict bool       // reject unknown fields, see LoadClusterSpecStrict
+	Values SpecValues // values for ${name} variables
+	Env    bool       // use environment variables for variables which are not in Values
+}
+
+// specVariable matches ${name}, and $${name} which is left as ${name}
+var specVariable = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_.-]*)\}`)
+
+// LoadSpecValues reads variable values from a YAML or JSON file. Nested keys are joined with dots, so
+// team: {name: a} sets ${team.name}
+func LoadSpecValues(path string) (SpecValues, error) {
+	Debug(1, "Entered LoadSpecValues for "+path)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	err = yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, errors.New("Cannot read values file " + path + ": " + err.Error())
+	}
+
+	values := SpecValues{}
+	if len(document.Content) == 0 {
+		return values, nil
+	}
+	err = values.addYAML("", document.Content[0], &yamlAliases{})
+	if err != nil {
+		return nil, errors.New("Cannot read values file " + path + ": " + err.Error())
+	}
+
+	return values, nil
+}
+
+func (v SpecValues) addYAML(prefix string, node *yaml.Node, aliases *yamlAliases) error {
+
+	if node.Kind == yaml.AliasNode {
+		if err := aliases.enter(node); err != nil {
+			return errors.New("line " + strconv.Itoa(err.Line) + ": " + err.Message)
+		}
+		defer aliases.leave(node)
+		return v.addYAML(prefix, node.Alias, aliases)
+	}
+	if err := aliases.add(); err != nil {
+		return errors.New("line " + strconv.Itoa(err.Line) + ": " + err.Message)
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			err := v.addYAML(name, node.Content[i+1], aliases)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if prefix == "" {
+			return errors.New("values must be a map of names to values")
+		}
+		v[prefix] = node.Value
+	default:
+		return errors.New("line " + strconv.Itoa(node.Line) + ": value of " + prefix + " must be a single value, not a list")
+	}
+
+	return nil
+}
+
+// Set sets a value from a name=value string, as given to --set
+func (v SpecValues) Set(assignment string) error {
+	parts := strings.SplitN(assignment, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return errors.New("Value must be given as name=value: " + assignment)
+	}
+	v[parts[0]] = parts[1]
+	return nil
+}
+
+// lookup finds a variable in the values, then the environment when options.Env is set
+func (o *SpecOptions) lookup(name string) (string, bool) {
+	if value, ok := o.Values[name]; ok {
+		return value, true
+	}
+	if o.Env {
+		return os.LookupEnv(name)
+	}
+	return "", false
+}
+
+// RenderClusterSpec fills in the ${name} variables in a spec file and gives the specs back as JSON, one document
+// per line. Variables are filled in after the file is parsed and only in string values, so a value is never read
+// as part of the file and variables in comments are left alone. A string which is just one variable takes the type
+// the value has in YAML, so size: ${workers} or "size": "${workers}" gives a number, and "${team}-cluster" is a
+// string. Every variable which has no value is returned as a SpecError with its line and column
+func RenderClusterSpec(name string, data []byte, options SpecOptions) ([]byte, error) {
+
+	documents, err := renderSpecDocuments(name, data, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var rendered bytes.Buffer
+	decoder := specDecoder{file: name}
+	for _, document := range documents {
+		body, err := json.Marshal(decoder.generic(document))
+		if err != nil {
+			return nil, err
+		}
+		rendered.Write(body)
+		rendered.WriteByte('\n')
+	}
+
+	return rendered.Bytes(), nil
+}
+
+// LoadClusterSpecWithOptions reads the cluster specs in a JSON or YAML file like LoadClusterSpec, filling in
+// ${name} variables from options as RenderClusterSpec does
+func LoadClusterSpecWithOptions(path string, options SpecOptions) ([]ClusterSpec, error) {
+	Debug(1, "Entered LoadClusterSpecWithOptions for "+path)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseClusterSpecWithOptions(path, data, options)
+}
+
+// ParseClusterSpecWithOptions parses the cluster specs in data like ParseClusterSpec, filling in ${name} variables
+// from options as RenderClusterSpec does
+func ParseClusterSpecWithOptions(name string, data []byte, options SpecOptions) ([]ClusterSpec, error) {
+
+	documents, err := renderSpecDocuments(name, data, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeClusterSpecs(name, documents, options.Strict)
+}
+
+// renderSpecDocuments parses data and fills in the variables in every document
+func renderSpecDocuments(name string, data []byte, options SpecOptions) ([]*specNode, error) {
+
+	documents, err := parseSpecDocuments(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	renderer := specRenderer{file: name, options: &options}
+	for i, document := range documents {
+		renderer.document = i + 1
+		renderer.render(document)
+	}
+	if len(renderer.problems) > 0 {
+		return nil, renderer.problems
+	}
+
+	return documents, nil
+}
+
+// specRenderer fills in variables in parsed spec documents, collecting the variables which have no value
+type specRenderer struct {
+	file     string
+	document int
+	options  *SpecOptions
+	problems SpecErrors
+}
+
+func (r *specRenderer) render(node *specNode) {
+	switch node.Kind {
+	case specObject:
+		for _, field := range node.Fields {
+			r.render(field.Value)
+		}
+	case specArray:
+		for _, item := range node.Items {
+			r.render(item)
+		}
+	case specString:
+		r.renderString(node)
+	}
+}
+
+func (r *specRenderer) renderString(node *specNode) {
+
+	text := node.Value.(string)
+	if !strings.Contains(text, "${") {
+		return
+	}
+
+	missing := func(variable string) {
+		r.problems = append(r.problems, &SpecError{File: r.file, Document: r.document, Line: node.Line, Column: node.Column,
+			Message: "variable ${" + variable + "} is not set"})
+	}
+
+	// a string which is just one variable takes the type of its value
+	loc := specVariable.FindStringSubmatchIndex(text)
+	if loc != nil && loc[0] == 0 && loc[1] == len(text) && !strings.HasPrefix(text, "$$") {
+		variable := text[loc[2]:loc[3]]
+		value, ok := r.options.lookup(variable)
+		if !ok {
+			missing(variable)
+			return
+		}
+		typed := specValueNode(value)
+		typed.Line, typed.Column = node.Line, node.Column
+		*node = *typed
+		return
+	}
+
+	rendered := specVariable.ReplaceAllStringFunc(text, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		variable := match[2 : len(match)-1]
+		if value, ok := r.options.lookup(variable); ok {
+			return value
+		}
+		missing(variable)
+		return match
+	})
+	node.Value = rendered
+	node.Text = rendered
+}
+
+// specValueNode is a variable value as a spec value. Numbers, true and false and null are typed as in YAML and
+// can still be used for a string, anything else is a string as it is
+func specValueNode(value string) *specNode {
+	var document yaml.Node
+	if yaml.Unmarshal([]byte(value), &document) == nil && len(document.Content) == 1 {
+		scalar := document.Content[0]
+		if scalar.Kind == yaml.ScalarNode && scalar.ShortTag() != "!!str" && scalar.Style == 0 {
+			if node, err := yamlSpecNode(scalar); err == nil {
+				node.Text = value
+				return node
+			}
+		}
+	}
+	return &specNode{Kind: specString, Value: value, Text: value}
+}
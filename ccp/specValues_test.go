@@ -0,0 +1,171 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRenderClusterSpec(t *testing.T) {
+
+	values := SpecValues{
+		"team":    "blue",
+		"workers": "5",
+		"version": "1.16",
+		"inject":  "x\", \"description\": \"injected",
+		"yaml":    "x\ndescription: injected",
+	}
+
+	tests := []struct {
+		file string
+		data string
+		want string
+	}{
+		{"spec.yaml", "name: ${team}-cluster\nsize: ${workers}\n", `{"name":"blue-cluster","size":5}`},
+		{"spec.json", `{"name": "${team}-cluster", "size": "${workers}"}`, `{"name":"blue-cluster","size":5}`},
+		{"spec.yaml", "name: ${team}\n# size: ${unset}\n", `{"name":"blue"}`},
+		{"spec.yaml", "name: $${team}\n", `{"name":"${team}"}`},
+		{"spec.json", `{"name": "${inject}"}`, `{"name":"x\", \"description\": \"injected"}`},
+		{"spec.yaml", "name: ${yaml}\n", `{"name":"x\ndescription: injected"}`},
+		{"spec.yaml", "name: one\n---\nname: ${team}\n", "{\"name\":\"one\"}\n{\"name\":\"blue\"}"},
+	}
+
+	for _, test := range tests {
+		rendered, err := RenderClusterSpec(test.file, []byte(test.data), SpecOptions{Values: values})
+		if err != nil {
+			t.Errorf("RenderClusterSpec(%q) error %v", test.data, err)
+			continue
+		}
+		if got := strings.TrimSpace(string(rendered)); got != test.want {
+			t.Errorf("RenderClusterSpec(%q) = %s, want %s", test.data, got, test.want)
+		}
+	}
+}
+
+func TestRenderClusterSpecMissing(t *testing.T) {
+
+	data := "name: ${team}\n# ${comment}\ndescription: ${owner} and ${team}\n"
+	_, err := RenderClusterSpec("spec.yaml", []byte(data), SpecOptions{Values: SpecValues{}})
+
+	var problems SpecErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("RenderClusterSpec error %v, want SpecErrors", err)
+	}
+	want := []string{
+		"spec.yaml:1:7: variable ${team} is not set",
+		"spec.yaml:3:14: variable ${owner} is not set",
+		"spec.yaml:3:14: variable ${team} is not set",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("RenderClusterSpec problems %v, want %v", problems, want)
+	}
+	for i, problem := range problems {
+		if problem.Error() != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, problem.Error(), want[i])
+		}
+	}
+}
+
+func TestParseClusterSpecWithOptions(t *testing.T) {
+
+	data := []byte("name: ${team}\nkubernetes_version: ${version}\ndescription: ${workers}\n")
+	options := SpecOptions{Values: SpecValues{"team": "blue", "version": "1.16", "workers": "5"}, Strict: true}
+
+	specs, err := ParseClusterSpecWithOptions("spec.yaml", data, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 1 {
+		t.Fatalf("ParseClusterSpecWithOptions gave %d specs, want 1", len(specs))
+	}
+	spec := specs[0]
+	// numbers from variables can still be used for string fields
+	if *spec.Name != "blue" || *spec.KubernetesVersion != "1.16" || *spec.Description != "5" {
+		t.Errorf("ParseClusterSpecWithOptions = %s %s %s", *spec.Name, *spec.KubernetesVersion, *spec.Description)
+	}
+}
+
+func TestSpecValuesSet(t *testing.T) {
+
+	values := SpecValues{}
+	for _, assignment := range []string{"a=1", "b=x=y", "c="} {
+		if err := values.Set(assignment); err != nil {
+			t.Errorf("Set(%q) error %v", assignment, err)
+		}
+	}
+	if values["a"] != "1" || values["b"] != "x=y" || values["c"] != "" {
+		t.Errorf("Set gave %v", values)
+	}
+	for _, assignment := range []string{"a", "=1"} {
+		if err := values.Set(assignment); err == nil {
+			t.Errorf("Set(%q) did not fail", assignment)
+		}
+	}
+}
+
+func TestLoadSpecValuesAliases(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "values")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var laughs strings.Builder
+	laughs.WriteString("a: &a {k0: x, k1: x, k2: x, k3: x, k4: x, k5: x, k6: x, k7: x, k8: x, k9: x}\n")
+	for i := 'b'; i <= 'i'; i++ {
+		laughs.WriteString(string(i) + ": &" + string(i) + " {")
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				laughs.WriteString(", ")
+			}
+			laughs.WriteString("k" + strconv.Itoa(j) + ": *" + string(i-1))
+		}
+		laughs.WriteString("}\n")
+	}
+
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"team: &a\n  name: *a\n", "line 2: alias *a is inside its own anchor"},
+		{laughs.String(), "aliases expand to more than 10000 values"},
+		{"size: &a 5\nworkers: *a\n", ""},
+	}
+
+	for i, test := range tests {
+		path := filepath.Join(dir, "values"+strconv.Itoa(i)+".yaml")
+		if err := ioutil.WriteFile(path, []byte(test.data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		values, err := LoadSpecValues(path)
+		if test.err == "" {
+			if err != nil || values["workers"] != "5" {
+				t.Errorf("LoadSpecValues(%q) = %v, %v", test.data, values, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("LoadSpecValues(%q) error %v, want %q", test.data, err, test.err)
+		}
+	}
+}
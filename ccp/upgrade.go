@@ -0,0 +1,410 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// UpgradeStatus is reported while UpgradeCluster moves through each stage
+type UpgradeStatus struct {
+	Stage   string // "masters" or the worker node pool name
+	State   string // "patching", "waiting", "done"
+	Message string
+}
+
+// KubeVersion is a parsed Kubernetes version such as 1.16.3
+type KubeVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+func (v KubeVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// ParseKubeVersion parses a Kubernetes version such as "1.16.3" or "v1.16.3"
+func ParseKubeVersion(version string) (*KubeVersion, error) {
+
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, errors.New("Invalid Kubernetes version '" + version + "'")
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, errors.New("Invalid Kubernetes version '" + version + "'")
+		}
+		numbers[i] = number
+	}
+
+	return &KubeVersion{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// CheckUpgradePath checks that moving from the current to the target Kubernetes version is supported.
+// Downgrades, major version changes and skipped minor versions are refused
+func CheckUpgradePath(current, target string) error {
+
+	from, err := ParseKubeVersion(current)
+	if err != nil {
+		return err
+	}
+	to, err := ParseKubeVersion(target)
+	if err != nil {
+		return err
+	}
+
+	if from.Major != to.Major {
+		return errors.New("Upgrade from " + current + " to " + target + " changes the major version, which is not supported")
+	}
+	if to.Minor < from.Minor || (to.Minor == from.Minor && to.Patch < from.Patch) {
+		return errors.New("Upgrade from " + current + " to " + target + " is a downgrade, which is not supported")
+	}
+	if to.Minor-from.Minor > 1 {
+		return fmt.Errorf("Upgrade from %s to %s skips %d minor version(s), upgrade one minor version at a time", current, target, to.Minor-from.Minor-1)
+	}
+
+	return nil
+}
+
+// UpgradeCluster upgrades a cluster to the Kubernetes version of targetTemplate. The masters are
+// upgraded first and then each worker node pool, waiting for every stage to finish before the next
+func (s *Client) UpgradeCluster(ctx context.Context, clusterUUID, targetTemplate string) (*Cluster, error) {
+	return s.UpgradeClusterWithProgress(ctx, clusterUUID, targetTemplate, func(status UpgradeStatus) {
+		Debug(1, "Upgrade "+status.Stage+" "+status.State+": "+status.Message)
+	})
+}
+
+// UpgradeClusterWithProgress is UpgradeCluster but calls progress each time the upgrade status changes
+func (s *Client) UpgradeClusterWithProgress(ctx context.Context, clusterUUID, targetTemplate string, progress func(UpgradeStatus)) (*Cluster, error) {
+	Debug(1, "Entered UpgradeCluster for UUID "+clusterUUID+" to template "+targetTemplate)
+
+	if clusterUUID == "" {
+		return nil, errors.New("Cluster UUID is required")
+	}
+	if progress == nil {
+		progress = func(UpgradeStatus) {}
+	}
+
+	targetVersion := GetKubeVerFromImage(targetTemplate)
+	if targetVersion == "" {
+		return nil, errors.New("Cannot find the Kubernetes version in template name " + targetTemplate)
+	}
+
+	cluster, err := s.GetClusterByUUID(clusterUUID)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.KubernetesVersion == nil {
+		return nil, errors.New("Cluster " + clusterUUID + " has no Kubernetes version")
+	}
+	if cluster.MasterNodePool == nil {
+		return nil, errors.New("Cluster " + clusterUUID + " has no master node pool")
+	}
+
+	err = CheckUpgradePath(*cluster.KubernetesVersion, targetVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// worker pools are patched by name, so stop before anything changes if one which needs upgrading has none
+	if cluster.WorkerNodePool != nil {
+		for i, pool := range *cluster.WorkerNodePool {
+			if pool.Name == nil && poolNeedsUpgrade(pool.Template, pool.KubernetesVersion, targetTemplate, targetVersion) {
+				return nil, fmt.Errorf("Worker node pool %d of cluster %s has no name, so it cannot be upgraded", i+1, clusterUUID)
+			}
+		}
+	}
+
+	// masters first
+	if poolNeedsUpgrade(cluster.MasterNodePool.Template, cluster.MasterNodePool.KubernetesVersion, targetTemplate, targetVersion) {
+		progress(UpgradeStatus{Stage: "masters", State: "patching", Message: "upgrading to " + targetVersion})
+
+		// the old masters are still ready straight after the patch, so the stage is done when they are all replaced
+		oldNodes := nodeNameSet(cluster.MasterNodePool.Nodes)
+
+		_, err = s.PatchCluster(&Cluster{
+			KubernetesVersion: String(targetVersion),
+			MasterNodePool: &MasterNodePool{
+				Template:          String(targetTemplate),
+				KubernetesVersion: String(targetVersion),
+			},
+		}, clusterUUID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.waitForUpgradeStage(ctx, clusterUUID, "masters", targetVersion, oldNodes, progress, func(c *Cluster) (*[]Node, *int64, *string) {
+			if c.MasterNodePool == nil {
+				return nil, nil, nil
+			}
+			return c.MasterNodePool.Nodes, c.MasterNodePool.Size, c.MasterNodePool.KubernetesVersion
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	progress(UpgradeStatus{Stage: "masters", State: "done", Message: "running " + targetVersion})
+
+	// then each worker node pool in turn
+	if cluster.WorkerNodePool != nil {
+		for _, pool := range *cluster.WorkerNodePool {
+			if pool.Name == nil {
+				continue // already on the target, checked above
+			}
+			poolName := *pool.Name
+
+			if poolNeedsUpgrade(pool.Template, pool.KubernetesVersion, targetTemplate, targetVersion) {
+				progress(UpgradeStatus{Stage: poolName, State: "patching", Message: "upgrading to " + targetVersion})
+
+				oldNodes := nodeNameSet(pool.Nodes)
+
+				err = s.patchNodePoolVersion(clusterUUID, poolName, targetTemplate, targetVersion)
+				if err != nil {
+					return nil, err
+				}
+
+				err = s.waitForUpgradeStage(ctx, clusterUUID, poolName, targetVersion, oldNodes, progress, func(c *Cluster) (*[]Node, *int64, *string) {
+					p := findWorkerNodePool(c, poolName)
+					if p == nil {
+						return nil, nil, nil
+					}
+					return p.Nodes, p.Size, p.KubernetesVersion
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			progress(UpgradeStatus{Stage: poolName, State: "done", Message: "running " + targetVersion})
+		}
+	}
+
+	return s.GetClusterByUUID(clusterUUID)
+}
+
+// poolNeedsUpgrade checks if a pool is not yet on the target template and version
+func poolNeedsUpgrade(template, version *string, targetTemplate, targetVersion string) bool {
+	if template == nil || *template != targetTemplate {
+		return true
+	}
+	return version == nil || *version != targetVersion
+}
+
+// patchNodePoolVersion moves a worker node pool to a new template and Kubernetes version
+func (s *Client) patchNodePoolVersion(clusterUUID, poolName, template, version string) error {
+
+	url := s.BaseURL + "/v3/clusters/" + clusterUUID + "/node-pools/" + poolName + "/"
+
+	j, err := json.Marshal(WorkerNodePool{
+		Name:              String(poolName),
+		Template:          String(template),
+		KubernetesVersion: String(version),
+	})
+	if err != nil {
+		return err
+	}
+	Debug(3, "Sending JSON patch: "+string(j))
+
+	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(j))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.doRequest(req)
+	return err
+}
+
+// nodeNameSet returns the names of the nodes, nodes without a name are left out
+func nodeNameSet(nodes *[]Node) map[string]bool {
+	names := map[string]bool{}
+	if nodes == nil {
+		return names
+	}
+	for _, node := range *nodes {
+		if node.Name != nil && *node.Name != "" {
+			names[*node.Name] = true
+		}
+	}
+	return names
+}
+
+// waitForUpgradeStage waits until the pool returned by get reports the target version, none of oldNodes are left
+// and the pool has its full size of new nodes which are all ready
+func (s *Client) waitForUpgradeStage(ctx context.Context, clusterUUID, stage, targetVersion string, oldNodes map[string]bool, progress func(UpgradeStatus), get func(*Cluster) (*[]Node, *int64, *string)) error {
+
+	lastMessage := ""
+
+	err := poll(ctx, ClusterPollInterval, func() (bool, error) {
+		cluster, err := s.GetClusterByUUID(clusterUUID)
+		if err != nil {
+			return false, err
+		}
+
+		nodes, size, version := get(cluster)
+		if size == nil {
+			return false, errors.New("Pool " + stage + " not found in cluster " + clusterUUID)
+		}
+
+		count, ready, failed := countNodes(nodes)
+		if len(failed) > 0 {
+			return false, &NodePoolError{Pool: stage, Nodes: failed}
+		}
+
+		left := 0
+		for name := range nodeNameSet(nodes) {
+			if oldNodes[name] {
+				left++
+			}
+		}
+
+		current := "unknown"
+		if version != nil {
+			current = *version
+		}
+		message := fmt.Sprintf("version %s, %d of %d nodes ready, %d old nodes left", current, ready, *size, left)
+		if message != lastMessage {
+			progress(UpgradeStatus{Stage: stage, State: "waiting", Message: message})
+			lastMessage = message
+		}
+
+		return current == targetVersion && left == 0 && int64(ready) == *size && int64(count) == *size, nil
+	})
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("waiting for upgrade of %s (%s): %w", stage, lastMessage, err)
+	}
+	return err
+}
+
+// UpgradePlan reports what UpgradeCluster would do to a cluster, without changing anything
+type UpgradePlan struct {
+	ClusterName    string   `json:"cluster"`
+	ClusterUUID    string   `json:"id"`
+	CurrentVersion string   `json:"current_version"`
+	TargetVersion  string   `json:"target_version"`
+	TargetTemplate string   `json:"target_template"`
+	Pools          []string `json:"pools"` // "masters" and the names of the worker node pools to upgrade
+	NodesToReplace int64    `json:"nodes_to_replace"`
+	Blockers       []string `json:"blockers,omitempty"`
+}
+
+// Blocked checks if anything would stop the upgrade
+func (p UpgradePlan) Blocked() bool {
+	return len(p.Blockers) > 0
+}
+
+// PlanUpgrade builds an UpgradePlan for each cluster as a dry-run report of upgrading them to targetTemplate.
+// Blockers are failed nodes, addons not in INSTALLED state and unsupported upgrade paths such as minor version skips
+func (s *Client) PlanUpgrade(clusters []Cluster, targetTemplate string) ([]UpgradePlan, error) {
+	Debug(1, "Entered PlanUpgrade for template "+targetTemplate)
+
+	targetVersion := GetKubeVerFromImage(targetTemplate)
+	if targetVersion == "" {
+		return nil, errors.New("Cannot find the Kubernetes version in template name " + targetTemplate)
+	}
+
+	var plans []UpgradePlan
+
+	for i := range clusters {
+		cluster := &clusters[i]
+
+		plan := UpgradePlan{
+			TargetVersion:  targetVersion,
+			TargetTemplate: targetTemplate,
+			Pools:          []string{},
+		}
+		if cluster.Name != nil {
+			plan.ClusterName = *cluster.Name
+		}
+		if cluster.UUID != nil {
+			plan.ClusterUUID = *cluster.UUID
+		}
+
+		if cluster.KubernetesVersion == nil {
+			plan.Blockers = append(plan.Blockers, "cluster has no Kubernetes version")
+		} else {
+			plan.CurrentVersion = *cluster.KubernetesVersion
+			err := CheckUpgradePath(plan.CurrentVersion, targetVersion)
+			if err != nil {
+				plan.Blockers = append(plan.Blockers, err.Error())
+			}
+		}
+
+		if cluster.MasterNodePool != nil {
+			master := cluster.MasterNodePool
+			if poolNeedsUpgrade(master.Template, master.KubernetesVersion, targetTemplate, targetVersion) {
+				plan.Pools = append(plan.Pools, "masters")
+				plan.NodesToReplace += poolNodeCount(master.Size, master.Nodes)
+			}
+			_, _, failed := countNodes(master.Nodes)
+			for _, node := range failed {
+				plan.Blockers = append(plan.Blockers, "master node "+nodeName(node)+" failed: "+nodeReason(node))
+			}
+		}
+
+		if cluster.WorkerNodePool != nil {
+			for _, pool := range *cluster.WorkerNodePool {
+				poolName := "<unnamed>"
+				if pool.Name != nil {
+					poolName = *pool.Name
+				}
+				if poolNeedsUpgrade(pool.Template, pool.KubernetesVersion, targetTemplate, targetVersion) {
+					plan.Pools = append(plan.Pools, poolName)
+					plan.NodesToReplace += poolNodeCount(pool.Size, pool.Nodes)
+				}
+				_, _, failed := countNodes(pool.Nodes)
+				for _, node := range failed {
+					plan.Blockers = append(plan.Blockers, "node "+nodeName(node)+" in pool "+poolName+" failed: "+nodeReason(node))
+				}
+			}
+		}
+
+		if plan.ClusterUUID != "" {
+			addons, err := s.GetClusterInstalledAddons(plan.ClusterUUID)
+			if err != nil {
+				plan.Blockers = append(plan.Blockers, "cannot list addons: "+err.Error())
+			} else {
+				for _, addon := range addons.Results {
+					if addon.AddonStatus.Status != "INSTALLED" {
+						plan.Blockers = append(plan.Blockers, "addon "+addon.Name+" is "+addon.AddonStatus.Status)
+					}
+				}
+			}
+		}
+
+		plans = append(plans, plan)
+	}
+
+	return plans, nil
+}
+
+// poolNodeCount is the pool size, or the number of nodes if no size is reported
+func poolNodeCount(size *int64, nodes *[]Node) int64 {
+	if size != nil {
+		return *size
+	}
+	count, _, _ := countNodes(nodes)
+	return int64(count)
+}
@@ -0,0 +1,228 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckUpgradePath(t *testing.T) {
+
+	tests := []struct {
+		current string
+		target  string
+		wantErr string
+	}{
+		{"1.15.5", "1.15.5", ""},
+		{"1.15.5", "1.15.7", ""},
+		{"1.15.5", "1.16.3", ""},
+		{"v1.15.5", "1.16", ""},
+		{"1.15.5", "1.17.0", "skips 1 minor version"},
+		{"1.16.3", "1.15.5", "downgrade"},
+		{"1.16.3", "1.16.1", "downgrade"},
+		{"1.16.3", "2.0.0", "major version"},
+		{"1.16.x", "1.16.3", "Invalid Kubernetes version"},
+		{"1", "1.16.3", "Invalid Kubernetes version"},
+	}
+
+	for _, test := range tests {
+		err := CheckUpgradePath(test.current, test.target)
+		if test.wantErr == "" {
+			if err != nil {
+				t.Errorf("CheckUpgradePath(%s, %s) = %v", test.current, test.target, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("CheckUpgradePath(%s, %s) = %v, want %q", test.current, test.target, err, test.wantErr)
+		}
+	}
+}
+
+// upgradeServer is a cluster with one master and one worker which CCP replaces a few polls after each patch.
+// The old node stays ready until it is replaced, as it does in CCP
+type upgradeServer struct {
+	mu       sync.Mutex
+	version  map[string]string // pool name to Kubernetes version
+	polls    map[string]int    // polls since the pool was patched, -1 before
+	replaced map[string]bool
+	unnamed  bool // add a worker pool with no name which is not upgraded yet
+	patches  int
+}
+
+const upgradeFrom = "ccp-tenant-image-1.15.5-ubuntu18-6.1.1"
+const upgradeTo = "ccp-tenant-image-1.16.3-ubuntu18-6.1.1"
+
+func (u *upgradeServer) pool(name string) (*string, *string, *[]Node) {
+	template := upgradeFrom
+	node := name + "-old"
+	if u.polls[name] >= 0 {
+		u.polls[name]++
+		// CCP reports the new version straight away but takes a while to replace the node
+		if u.polls[name] > 3 {
+			template = upgradeTo
+			node = name + "-new"
+			u.replaced[name] = true
+		}
+	}
+	return String(template), String(u.version[name]), &[]Node{{Name: String(node), Status: String("READY")}}
+}
+
+func (u *upgradeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	switch {
+	case r.Method == "PATCH" && strings.HasSuffix(r.URL.Path, "/node-pools/workers/"):
+		u.patches++
+		u.version["workers"] = "1.16.3"
+		u.polls["workers"] = 0
+		w.Write([]byte(`{}`))
+	case r.Method == "PATCH":
+		u.patches++
+		u.version["masters"] = "1.16.3"
+		u.polls["masters"] = 0
+		w.Write([]byte(`{}`))
+	case r.Method == "GET":
+		cluster := Cluster{UUID: String("uuid"), KubernetesVersion: String(u.version["masters"])}
+		template, version, nodes := u.pool("masters")
+		cluster.MasterNodePool = &MasterNodePool{Size: Int64(1), Template: template, KubernetesVersion: version, Nodes: nodes}
+		template, version, nodes = u.pool("workers")
+		cluster.WorkerNodePool = &[]WorkerNodePool{{Name: String("workers"), Size: Int64(1), Template: template, KubernetesVersion: version, Nodes: nodes}}
+		if u.unnamed {
+			*cluster.WorkerNodePool = append(*cluster.WorkerNodePool, WorkerNodePool{Size: Int64(1), Template: String(upgradeFrom), KubernetesVersion: String("1.15.5")})
+		}
+		json.NewEncoder(w).Encode(cluster)
+	}
+}
+
+func TestUpgradeClusterWaitsForNodesToBeReplaced(t *testing.T) {
+
+	interval := ClusterPollInterval
+	ClusterPollInterval = time.Millisecond
+	defer func() { ClusterPollInterval = interval }()
+
+	server := &upgradeServer{
+		version:  map[string]string{"masters": "1.15.5", "workers": "1.15.5"},
+		polls:    map[string]int{"masters": -1, "workers": -1},
+		replaced: map[string]bool{},
+	}
+	client := newTestClient(t, server)
+
+	var stages []string
+	_, err := client.UpgradeClusterWithProgress(context.Background(), "uuid", upgradeTo, func(status UpgradeStatus) {
+		if status.State != "done" {
+			return
+		}
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		if !server.replaced[status.Stage] {
+			t.Errorf("stage %s done before its node was replaced", status.Stage)
+		}
+		stages = append(stages, status.Stage)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(stages, ",") != "masters,workers" {
+		t.Errorf("stages done in order %v, want masters then workers", stages)
+	}
+}
+
+func TestUpgradeClusterUnnamedPool(t *testing.T) {
+
+	server := &upgradeServer{
+		version:  map[string]string{"masters": "1.15.5", "workers": "1.15.5"},
+		polls:    map[string]int{"masters": -1, "workers": -1},
+		replaced: map[string]bool{},
+		unnamed:  true,
+	}
+	client := newTestClient(t, server)
+
+	_, err := client.UpgradeCluster(context.Background(), "uuid", upgradeTo)
+	if err == nil || !strings.Contains(err.Error(), "Worker node pool 2 of cluster uuid has no name") {
+		t.Errorf("UpgradeCluster with an unnamed pool error %v", err)
+	}
+	if server.patches != 0 {
+		t.Errorf("UpgradeCluster with an unnamed pool sent %d patches, want none", server.patches)
+	}
+}
+
+func TestPlanUpgrade(t *testing.T) {
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/clusters/ok/addons/":
+			w.Write([]byte(`{"count": 1, "results": [{"name": "ccp-monitor", "status": {"status": "INSTALLED"}}]}`))
+		case "/v3/clusters/blocked/addons/":
+			w.Write([]byte(`{"count": 1, "results": [{"name": "ccp-efk", "status": {"status": "INSTALLING"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	ready := &[]Node{{Name: String("n1"), Status: String("READY")}, {Name: String("n2"), Status: String("READY")}}
+	failed := &[]Node{{Name: String("n3"), Status: String("ERROR"), StatusReason: String("no IP")}}
+
+	clusters := []Cluster{
+		{
+			Name:              String("ok"),
+			UUID:              String("ok"),
+			KubernetesVersion: String("1.15.5"),
+			MasterNodePool:    &MasterNodePool{Size: Int64(1), Template: String(upgradeFrom), KubernetesVersion: String("1.15.5")},
+			WorkerNodePool: &[]WorkerNodePool{
+				{Name: String("workers"), Size: Int64(2), Template: String(upgradeFrom), KubernetesVersion: String("1.15.5"), Nodes: ready},
+				{Name: String("done"), Size: Int64(3), Template: String(upgradeTo), KubernetesVersion: String("1.16.3")},
+			},
+		},
+		{
+			Name:              String("blocked"),
+			UUID:              String("blocked"),
+			KubernetesVersion: String("1.14.1"),
+			WorkerNodePool:    &[]WorkerNodePool{{Name: String("workers"), Size: Int64(1), Nodes: failed}},
+		},
+	}
+
+	plans, err := client.PlanUpgrade(clusters, upgradeTo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("PlanUpgrade returned %d plans, want 2", len(plans))
+	}
+
+	ok := plans[0]
+	if ok.Blocked() || strings.Join(ok.Pools, ",") != "masters,workers" || ok.NodesToReplace != 3 {
+		t.Errorf("plan for ok = %+v", ok)
+	}
+
+	blocked := plans[1]
+	want := []string{"skips 1 minor version", "node n3 in pool workers failed: ERROR - no IP", "addon ccp-efk is INSTALLING"}
+	if len(blocked.Blockers) != len(want) {
+		t.Fatalf("plan for blocked has blockers %q", blocked.Blockers)
+	}
+	for i, blocker := range blocked.Blockers {
+		if !strings.Contains(blocker, want[i]) {
+			t.Errorf("blocker %d = %q, want %q", i, blocker, want[i])
+		}
+	}
+}
@@ -0,0 +1,203 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ErrClusterNotVerified is returned by VerifyCluster when one of the checks failed
+var ErrClusterNotVerified = errors.New("Cluster failed verification")
+
+// VerifyReport is the result of checking a cluster through its Kubernetes API
+type VerifyReport struct {
+	Cluster         string   `json:"cluster"`
+	Server          string   `json:"server"`
+	Version         string   `json:"version,omitempty"` // gitVersion from /version
+	Ready           bool     `json:"ready"`             // /readyz answered ok
+	ExpectedNodes   int64    `json:"expected_nodes"`    // MasterNodePool.Size + sum(WorkerNodePool.Size)
+	RegisteredNodes int      `json:"registered_nodes"`
+	ReadyNodes      int      `json:"ready_nodes"`
+	NotReadyNodes   []string `json:"not_ready_nodes,omitempty"`
+	Errors          []string `json:"errors,omitempty"`
+}
+
+// OK checks if every check passed
+func (r *VerifyReport) OK() bool {
+	return len(r.Errors) == 0
+}
+
+// kubeNodeList is the part of the Kubernetes node list that is checked
+type kubeNodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// VerifyCluster checks a cluster through the Kubernetes API using its kubeconfig. It calls /version, /readyz and
+// lists the nodes, comparing the registered nodes with the pool sizes. The report is always returned when the
+// kubeconfig could be used, and the error wraps ErrClusterNotVerified if any check failed
+func VerifyCluster(ctx context.Context, cluster *Cluster) (*VerifyReport, error) {
+	return VerifyClusterWithClient(ctx, cluster, nil)
+}
+
+// VerifyClusterWithClient is VerifyCluster sending the requests with client, which is used as it is, so it must
+// carry its own credentials. The server is still taken from the kubeconfig. When client is nil the client built
+// from the kubeconfig credentials is used
+func VerifyClusterWithClient(ctx context.Context, cluster *Cluster, client *http.Client) (*VerifyReport, error) {
+
+	config, err := ClusterKubeConfig(cluster)
+	if err != nil {
+		return nil, err
+	}
+	Debug(1, "Entered VerifyCluster for "+*cluster.Name)
+
+	kubeClient, server, err := config.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = kubeClient
+	}
+
+	report := VerifyReport{
+		Cluster:       *cluster.Name,
+		Server:        server,
+		ExpectedNodes: expectedNodeCount(cluster),
+	}
+
+	// API server answers
+	body, err := kubeGet(ctx, client, server+"/version")
+	if err != nil {
+		report.Errors = append(report.Errors, "/version: "+err.Error())
+	} else {
+		var version struct {
+			GitVersion string `json:"gitVersion"`
+		}
+		if err := json.Unmarshal(body, &version); err != nil {
+			report.Errors = append(report.Errors, "/version: "+err.Error())
+		}
+		report.Version = version.GitVersion
+	}
+
+	// API server is ready
+	body, err = kubeGet(ctx, client, server+"/readyz")
+	if err != nil {
+		report.Errors = append(report.Errors, "/readyz: "+err.Error())
+	} else {
+		report.Ready = strings.TrimSpace(string(body)) == "ok"
+		if !report.Ready {
+			report.Errors = append(report.Errors, "/readyz: "+strings.TrimSpace(string(body)))
+		}
+	}
+
+	// all nodes registered and ready
+	body, err = kubeGet(ctx, client, server+"/api/v1/nodes")
+	if err != nil {
+		report.Errors = append(report.Errors, "/api/v1/nodes: "+err.Error())
+	} else {
+		var nodes kubeNodeList
+		if err := json.Unmarshal(body, &nodes); err != nil {
+			report.Errors = append(report.Errors, "/api/v1/nodes: "+err.Error())
+		}
+
+		report.RegisteredNodes = len(nodes.Items)
+		for _, node := range nodes.Items {
+			ready := false
+			for _, condition := range node.Status.Conditions {
+				if condition.Type == "Ready" && condition.Status == "True" {
+					ready = true
+				}
+			}
+			if ready {
+				report.ReadyNodes++
+			} else {
+				report.NotReadyNodes = append(report.NotReadyNodes, node.Metadata.Name)
+			}
+		}
+
+		if int64(report.RegisteredNodes) != report.ExpectedNodes {
+			report.Errors = append(report.Errors, fmt.Sprintf("%d nodes registered, expected %d", report.RegisteredNodes, report.ExpectedNodes))
+		}
+		if len(report.NotReadyNodes) > 0 {
+			report.Errors = append(report.Errors, "nodes not ready: "+strings.Join(report.NotReadyNodes, ", "))
+		}
+	}
+
+	if !report.OK() {
+		return &report, fmt.Errorf("%w: %s", ErrClusterNotVerified, strings.Join(report.Errors, "; "))
+	}
+
+	return &report, nil
+}
+
+// expectedNodeCount is the master pool size plus the size of every worker pool
+func expectedNodeCount(cluster *Cluster) int64 {
+	var count int64
+	if cluster.MasterNodePool != nil && cluster.MasterNodePool.Size != nil {
+		count += *cluster.MasterNodePool.Size
+	}
+	if cluster.WorkerNodePool != nil {
+		for _, pool := range *cluster.WorkerNodePool {
+			if pool.Size != nil {
+				count += *pool.Size
+			}
+		}
+	}
+	return count
+}
+
+// kubeGet does a GET against the Kubernetes API and returns the body of a 200 response
+func kubeGet(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	Debug(3, "GET "+url+" response: "+string(body))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
@@ -0,0 +1,141 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// kubeAPI is a fake Kubernetes API server with the given nodes, name to Ready condition status. When token is
+// set requests must carry it
+func kubeAPI(token, readyz string, nodes map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/version":
+			w.Write([]byte(`{"gitVersion": "v1.16.3"}`))
+		case "/readyz":
+			w.Write([]byte(readyz))
+		case "/api/v1/nodes":
+			var items []string
+			for name, ready := range nodes {
+				items = append(items, `{"metadata": {"name": "`+name+`"}, "status": {"conditions": [{"type": "Ready", "status": "`+ready+`"}]}}`)
+			}
+			w.Write([]byte(`{"items": [` + strings.Join(items, ",") + `]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// verifyCluster is a cluster of one master and two workers whose kubeconfig points at server
+func verifyCluster(server *httptest.Server, caData string) *Cluster {
+	kubeConfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: kubernetes
+  cluster:
+    server: ` + server.URL + `
+    certificate-authority-data: ` + caData + `
+users:
+- name: kubernetes-admin
+  user:
+    token: abc
+contexts:
+- name: kubernetes-admin@kubernetes
+  context:
+    cluster: kubernetes
+    user: kubernetes-admin
+current-context: kubernetes-admin@kubernetes
+`
+	return &Cluster{
+		Name:           String("mycluster"),
+		KubeConfig:     String(kubeConfig),
+		MasterNodePool: &MasterNodePool{Size: Int64(1)},
+		WorkerNodePool: &[]WorkerNodePool{{Name: String("workers"), Size: Int64(2)}},
+	}
+}
+
+func TestVerifyCluster(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		readyz     string
+		nodes      map[string]string
+		wantErrors []string
+	}{
+		{"healthy", "ok", map[string]string{"master": "True", "worker-a": "True", "worker-b": "True"}, nil},
+		{"not ready", "[-]etcd failed", map[string]string{"master": "True", "worker-a": "True", "worker-b": "False"}, []string{"/readyz: [-]etcd failed", "nodes not ready: worker-b"}},
+		{"missing node", "ok", map[string]string{"master": "True", "worker-a": "True"}, []string{"2 nodes registered, expected 3"}},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewTLSServer(kubeAPI("", test.readyz, test.nodes))
+
+		report, err := VerifyClusterWithClient(context.Background(), verifyCluster(server, ""), server.Client())
+		server.Close()
+
+		if report == nil {
+			t.Fatalf("%s: no report, error %v", test.name, err)
+		}
+		if report.Version != "v1.16.3" || report.ExpectedNodes != 3 {
+			t.Errorf("%s: report %+v", test.name, report)
+		}
+		if len(test.wantErrors) == 0 {
+			if err != nil || !report.OK() {
+				t.Errorf("%s: VerifyClusterWithClient = %v", test.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrClusterNotVerified) {
+			t.Errorf("%s: error %v does not wrap ErrClusterNotVerified", test.name, err)
+		}
+		if strings.Join(report.Errors, "|") != strings.Join(test.wantErrors, "|") {
+			t.Errorf("%s: errors %q, want %q", test.name, report.Errors, test.wantErrors)
+		}
+	}
+}
+
+func TestVerifyClusterUsesKubeConfigCredentials(t *testing.T) {
+
+	server := httptest.NewTLSServer(kubeAPI("abc", "ok", map[string]string{"master": "True", "worker-a": "True", "worker-b": "True"}))
+	defer server.Close()
+
+	// without the server certificate in the kubeconfig the TLS handshake fails
+	report, err := VerifyCluster(context.Background(), verifyCluster(server, ""))
+	if err == nil || report.OK() {
+		t.Error("VerifyCluster trusted a server certificate which is not in the kubeconfig")
+	}
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	report, err = VerifyCluster(context.Background(), verifyCluster(server, base64.StdEncoding.EncodeToString(ca)))
+	if err != nil {
+		t.Fatalf("VerifyCluster with the kubeconfig CA and token: %v", err)
+	}
+	if report.ReadyNodes != 3 {
+		t.Errorf("report %+v", report)
+	}
+}
@@ -0,0 +1,233 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ClusterPollInterval is how often cluster and node state is checked while waiting
+var ClusterPollInterval = 5 * time.Second
+
+// AddonPollInterval is how often addon state is checked while waiting
+var AddonPollInterval = 2 * time.Second
+
+// AddonInstallTimeout is how long InstallAddon waits for each addon it installs, so an addon installed with the
+// addons it needs and its dependencies gets this for each of them
+var AddonInstallTimeout = 5 * time.Minute
+
+// AddonDeleteTimeout is how long DeleteAddon waits for an addon, and the addons that need it, to be deleted
+var AddonDeleteTimeout = 5 * time.Minute
+
+// poll runs check every interval until it is done, returns an error, or the context is finished.
+// check is run once straight away before the first wait
+func poll(ctx context.Context, interval time.Duration, check func() (bool, error)) error {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitForClusterReady waits until the cluster status is READY. An error is returned straight away if the
+// status reports a failure
+func (s *Client) WaitForClusterReady(ctx context.Context, clusterUUID string) (*Cluster, error) {
+	Debug(1, "Entered WaitForClusterReady for UUID "+clusterUUID)
+
+	var cluster *Cluster
+	status := "unknown"
+
+	err := poll(ctx, ClusterPollInterval, func() (bool, error) {
+		var err error
+		cluster, err = s.GetClusterByUUID(clusterUUID)
+		if err != nil {
+			return false, err
+		}
+		if cluster.Status != nil {
+			status = *cluster.Status
+		}
+		Debug(2, "Cluster "+clusterUUID+" status "+status)
+
+		switch strings.ToUpper(status) {
+		case "READY":
+			return true, nil
+		case "ERROR", "FAILED":
+			return false, errors.New("Cluster " + clusterUUID + " status is " + status)
+		}
+		return false, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("waiting for cluster %s (status %s): %w", clusterUUID, status, err)
+		}
+		return nil, err
+	}
+
+	return cluster, nil
+}
+
+// AddonError is returned when an addon fails to install or does not finish in time. Status, HelmStatus and
+// Detail are the last addon status seen. Err is the context error when waiting timed out
+type AddonError struct {
+	Addon      string
+	Status     string
+	HelmStatus string
+	Detail     string
+	Err        error
+}
+
+func (e *AddonError) Error() string {
+
+	message := "Addon " + e.Addon
+	if e.Err != nil {
+		message += " did not install in time"
+	} else {
+		message += " failed to install"
+	}
+	message += " (status " + e.Status
+	if e.HelmStatus != "" {
+		message += ", helm status " + e.HelmStatus
+	}
+	message += ")"
+	if e.Detail != "" {
+		message += ": " + e.Detail
+	}
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+
+	return message
+}
+
+// Unwrap returns the context error, so errors.Is(err, context.DeadlineExceeded) works
+func (e *AddonError) Unwrap() error {
+	return e.Err
+}
+
+// addonFailed reports whether an addon status shows a failure. The status and Helm status are checked for
+// FAILED or ERROR states, and the detail for a failed or error message
+func addonFailed(status *Status) bool {
+
+	for _, state := range []*string{status.Status, status.HelmStatus} {
+		if state == nil {
+			continue
+		}
+		upper := strings.ToUpper(*state)
+		if strings.Contains(upper, "FAIL") || strings.Contains(upper, "ERROR") {
+			return true
+		}
+	}
+	if status.StatusDetail != nil {
+		detail := strings.ToLower(*status.StatusDetail)
+		if strings.Contains(detail, "failed") || strings.HasPrefix(detail, "error") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WaitForAddon waits up to timeout, or until ctx is done, for an addon to be INSTALLED. The addon may not be
+// listed straight after it is posted, so a missing addon is waited for too. If the addon fails or does not
+// finish in time the error is an *AddonError. A timeout of 0 waits as long as ctx allows
+func (s *Client) WaitForAddon(ctx context.Context, clusterUUID, addonName string, timeout time.Duration) (*Results, error) {
+	Debug(1, "Entered WaitForAddon for UUID "+clusterUUID+" addon "+addonName)
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var addon *Results
+	last := &AddonError{Addon: addonName, Status: "not listed"}
+
+	err := poll(ctx, AddonPollInterval, func() (bool, error) {
+		var err error
+		addon, err = s.GetClusterAddon(clusterUUID, addonName)
+		if errors.Is(err, ErrAddonNotInstalled) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if addon.AddonStatus == nil {
+			return false, nil
+		}
+
+		last = &AddonError{Addon: addonName}
+		if addon.AddonStatus.Status != nil {
+			last.Status = *addon.AddonStatus.Status
+		}
+		if addon.AddonStatus.HelmStatus != nil {
+			last.HelmStatus = *addon.AddonStatus.HelmStatus
+		}
+		if addon.AddonStatus.StatusDetail != nil {
+			last.Detail = *addon.AddonStatus.StatusDetail
+		}
+		Debug(2, "Addon "+addonName+" status "+last.Status+" helm status "+last.HelmStatus)
+
+		if addonFailed(addon.AddonStatus) {
+			return false, last
+		}
+		return strings.ToUpper(last.Status) == "INSTALLED", nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			last.Err = ctx.Err()
+			return nil, last
+		}
+		return nil, err
+	}
+
+	return addon, nil
+}
+
+// waitForAddonDeleted waits until the addon is no longer listed on the cluster
+func (s *Client) waitForAddonDeleted(ctx context.Context, clusterUUID, addonName string) error {
+	Debug(1, "Entered waitForAddonDeleted for UUID "+clusterUUID+" addon "+addonName)
+
+	err := poll(ctx, AddonPollInterval, func() (bool, error) {
+		_, err := s.GetClusterAddon(clusterUUID, addonName)
+		if errors.Is(err, ErrAddonNotInstalled) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("waiting for addon %s to be deleted: %w", addonName, err)
+	}
+
+	return err
+}
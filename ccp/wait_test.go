@@ -0,0 +1,130 @@
+/*Copyright (c) 2019 Cisco and/or its affiliates.
+
+This software is licensed to you under the terms of the Cisco Sample
+Code License, Version 1.0 (the "License"). You may obtain a copy of the
+License at
+
+               https://developer.cisco.com/docs/licenses
+
+All use of the material herein must be in accordance with the terms of
+the License. All rights not expressly granted by the License are
+reserved. Unless required by applicable law or agreed to separately in
+writing, software distributed under the License is distributed on an "AS
+IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+or implied.*/
+
+package ccp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAddonFailed(t *testing.T) {
+
+	tests := []struct {
+		status Status
+		want   bool
+	}{
+		{Status{}, false},
+		{Status{Status: String("INSTALLED"), HelmStatus: String("DEPLOYED")}, false},
+		{Status{Status: String("INSTALLING"), HelmStatus: String("PENDING_INSTALL")}, false},
+		{Status{Status: String("FAILED")}, true},
+		{Status{Status: String("INSTALL_FAILED")}, true},
+		{Status{Status: String("Error")}, true},
+		{Status{Status: String("INSTALLING"), HelmStatus: String("FAILED")}, true},
+		{Status{Status: String("INSTALLING"), StatusDetail: String("helm install failed: timed out")}, true},
+		{Status{Status: String("INSTALLING"), StatusDetail: String("Error: chart not found")}, true},
+		{Status{Status: String("INSTALLING"), StatusDetail: String("waiting for pods, no errors yet")}, false},
+	}
+
+	for _, test := range tests {
+		if got := addonFailed(&test.status); got != test.want {
+			t.Errorf("addonFailed(%v %v %v) = %v, want %v", test.status.Status, test.status.HelmStatus, test.status.StatusDetail, got, test.want)
+		}
+	}
+}
+
+// addonSequence lists one addon whose status is the next one in statuses on each request, "" for not listed.
+// The last status is kept
+func addonSequence(statuses ...string) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		mu.Unlock()
+
+		results := []Results{}
+		if status != "" {
+			detail := ""
+			if status == "FAILED" {
+				detail = "helm install failed"
+			}
+			results = append(results, Results{Name: String("ccp-monitor"), AddonStatus: &Status{Status: String(status), StatusDetail: String(detail)}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"count": len(results), "next": nil, "results": results})
+	})
+}
+
+func TestWaitForAddon(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	tests := []struct {
+		name     string
+		statuses []string
+		err      string
+		timeout  bool
+	}{
+		{"installed", []string{"INSTALLED"}, "", false},
+		{"not listed then installed", []string{"", "", "INSTALLING", "INSTALLED"}, "", false},
+		{"failed", []string{"INSTALLING", "FAILED"}, "failed to install (status FAILED): helm install failed", false},
+		{"timed out", []string{"", "INSTALLING"}, "did not install in time (status INSTALLING)", true},
+		{"never listed", []string{""}, "did not install in time (status not listed)", true},
+	}
+
+	for _, test := range tests {
+		client := newTestClient(t, addonSequence(test.statuses...))
+
+		addon, err := client.WaitForAddon(context.Background(), "cluster-1", "ccp-monitor", 50*time.Millisecond)
+		if test.err == "" {
+			if err != nil || addon == nil || *addon.AddonStatus.Status != "INSTALLED" {
+				t.Errorf("%s: WaitForAddon = %v, %v", test.name, addon, err)
+			}
+			continue
+		}
+
+		var addonErr *AddonError
+		if !errors.As(err, &addonErr) || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want an AddonError with %q", test.name, err, test.err)
+			continue
+		}
+		if errors.Is(err, context.DeadlineExceeded) != test.timeout {
+			t.Errorf("%s: error %v, timed out %v", test.name, err, test.timeout)
+		}
+	}
+}
+
+func TestWaitForAddonServerError(t *testing.T) {
+
+	fastAddonPolls(t)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "cluster not found", http.StatusNotFound)
+	}))
+
+	_, err := client.WaitForAddon(context.Background(), "cluster-1", "ccp-monitor", time.Second)
+	var addonErr *AddonError
+	if err == nil || errors.As(err, &addonErr) {
+		t.Errorf("WaitForAddon error %v, want the API error", err)
+	}
+}
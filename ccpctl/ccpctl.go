@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,14 +11,18 @@ import (
 	"log"
 	"os"
 	"os/user"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	// go get -u github.com/CiscoSE/ccp-client-library
 	"github.com/CiscoSE/ccp-client-library/ccp"
+	"github.com/CiscoSE/ccp-client-library/ccp/kubeconfig"
 )
 
 // user.Current().HomeDir
@@ -59,6 +65,12 @@ type Defaults struct {
 	CPSubnetDfl       string    `json:"cpsubnetdfl`        // Default Subnet name
 	CPSubnetDflUUID   string    `json:"cpsubnetdflUUID"`   // Default Subnet UUID
 	CPVSClusterDfl    string    `json:"cpvsclusterdfl"`    // Default vSphere Cluster
+	CPProtected       []string  `json:"cpprotected"`       // Cluster name patterns protected from deletion
+	CPAllowed         []string  `json:"cpallowed"`         // Cluster name patterns which may be deleted even if protected
+	CPPolicyFile      string    `json:"cppolicyfile"`      // Protection policy file, read on every run
+
+	// KubeConfigFiles maps a cluster name to the kubeconfig file getkubeconf --merge wrote, for delcluster
+	KubeConfigFiles map[string]string `json:"kubeconfigfiles"`
 }
 
 // todo:
@@ -144,21 +156,50 @@ func menuHelp() {
 			sshkey="<key>"			// sets SSH Public key
 			imagedfl=imagename		// sets CCP image name default
 			networkdfl=DV_VL1001		// vSphere PortGroup or DVS name
+			protected=prod-*,db-*		// cluster name patterns protected from deletion
+			allowed=prod-scratch-*		// cluster name patterns which may be deleted even if they match protected
+			policy=ccp-policy.yaml	// protection policy file with protected: and allowed: lists
 
 	Cluster operation commands
 		addcluster	<clustername> [provider=providername] [subnet=subnetname] [datastore=datastore] [datacenter=dc]
 			// Uses preconfigured defaults for provider, subnet, datastore, datacenter if not provided
-		delcluster <clustername>
+		delcluster <clustername> [--yes] [--force] [file=path]	// asks to type the cluster name unless --yes, --force deletes protected clusters
+											// removes the kubeconfig context from file and the file getkubeconf --merge used
 		getcluster <clustername>				// pulls cluster info - master node IP(s), Addon, # worker nodes
-		scalecluster <clustername> workers=# [pool=poolname]	// scale to this many worker nodes in a cluster
+		scalecluster <clustername> workers=# [pool=poolname] [--wait]	// scale to this many worker nodes in a cluster, pool is found automatically if there is only one
+											// --wait waits until the new nodes are ready
+		verifycluster <clustername>				// checks the Kubernetes API answers and all nodes registered and are ready
+		upgradecluster <clustername> image=ccpimage		// upgrade masters then each worker pool to the Kubernetes version of the image
+		upgrade-plan image=ccpimage [clusters=name,name]	// dry-run report of an upgrade, all clusters by default. json=true for JSON
+		apply -f <file|directory> [spec options]		// create or update clusters to match the JSON or YAML specs, a spec may list "addons"
+		diff -f <file|directory> [spec options]		// show what apply would change, and which changes need the cluster recreated
+		render -f <file|directory> [spec options]		// show the cluster payload sent to AddCluster after filling in ${variables}
+		addclusterfromfile <file> [spec options]		// create the clusters in a JSON or YAML file, YAML files can hold several separated by ---
+			spec options:
+			--strict					// reject unknown fields, e.g. node_group for node_groups
+			values=values.yaml				// values for ${name} variables in the spec
+			--set name=value				// overrides values.yaml, environment variables are used for anything else
+		export cluster <clustername> [file=path] [--ssh-keys]	// save the cluster as a spec for addclusterfromfile or apply, SSH keys are removed unless --ssh-keys
+
+	Cluster node pool commands
+		getpools <clustername>					// lists the worker node pools
+		addpool <clustername> name=poolname [workers=1] [vcpus=8] [memory=32768] [image=ccpimage] [gpus=type,type]
+		delpool <clustername> <poolname>
 
 	Cluster Addon commands
-		addclusteraddon <clustername> <addon>	// install an addon
-		delclusteraddon <clustername> <addon>	// install an addon
-		getclusteraddon <clustername> <addon>	// install an addon
+		installaddon <clustername> <addon> [-f values.yaml] [addons=file]	// install an addon with the addons it needs, from the cluster catalogue or a definition file
+											// a list such as monitoring,logging,dashboard installs independent addons at the same time
+											// -f sets Helm values for the addon, e.g. EFK retention or monitoring storage
+		upgradeaddon <clustername> <addon> [version=x]	// install the catalogue chart again if it changed or version is not installed
+		reconfigureaddon <clustername> <addon> -f values.yaml	// install the addon again if its Helm values changed
+		deladdon <clustername> <addon>		// delete an addon and the addons that need it, waiting until they are gone
+		getaddons <clustername>			// list the available and installed addons
+		installchart <clustername> name=chartname url=/opt/ccp/charts/chart.tgz [namespace=ns] [-f values.yaml]	// install a Helm chart staged under /opt/ccp/charts/ on the control plane as an addon
+		endpoints <clustername> [--open]	// list the service URL and namespace of each installed addon, --open prints clickable links only
 
 	Kubectl config commands
-		getkubeconf <clustername>  		// gets kubeconf
+		getkubeconf <clustername> [--merge] [--set-context] [file=path]	// prints kubeconf, --merge adds it to ~/.kube/config or file
+		certs [warn=30]				// lists the kubeconfig client certificate expiry of every cluster, warns if expiring within warn days
 
 	Debugging
 		debug=N			// Debug level 0 (default), 1 (info), 2 (function entry/exit and variables), 3 (full debug including JSON)
@@ -224,6 +265,9 @@ func menuHelpCP() {
 			datacenterdfl=dc 
 			vsclusterdfl=vsphereclustername
 			imagedfl=ccp-tenant-image-1.16.3-ubuntu18-6.1.1
+			protected=prod-*,db-*
+			allowed=prod-scratch-*
+			policy=/home/user/.ccp-policy.yaml
 	`)
 }
 
@@ -297,6 +341,30 @@ func menuSetCP(args []string, Settings Defaults, client *ccp.Client) (*Defaults,
 		case "networkdfl":
 			fmt.Println("network updated with: " + value)
 			Settings.CPNetworkDfl = value
+		case "protected":
+			fmt.Println("protected updated with: " + value)
+			if value == "" {
+				Settings.CPProtected = nil
+			} else {
+				Settings.CPProtected = strings.Split(value, ",")
+			}
+		case "allowed":
+			fmt.Println("allowed updated with: " + value)
+			if value == "" {
+				Settings.CPAllowed = nil
+			} else {
+				Settings.CPAllowed = strings.Split(value, ",")
+			}
+		case "policy":
+			if value != "" {
+				_, err := ccp.LoadProtectionPolicy(value)
+				if err != nil {
+					fmt.Println("* Error reading policy: ", err)
+					return nil, err
+				}
+			}
+			fmt.Println("policy updated with: " + value)
+			Settings.CPPolicyFile = value
 		default:
 			fmt.Println("Not understood: param=" + param + " value=" + value)
 			menuHelpCP()
@@ -435,7 +503,7 @@ func prettyPrintJSONClusterAddon(clusterAddon *ccp.ClusterInstalledAddons) {
 	}
 	fmt.Println(&prettyJSON)
 }
-func prettyPrintJSONClusterAddonCatalogue(clusterAddon *ccp.AddonsCatalogue) {
+func prettyPrintJSONClusterAddonCatalogue(clusterAddon ccp.AddonsCatalogue) {
 	var prettyJSON bytes.Buffer
 
 	jsonBody, err := json.Marshal(clusterAddon)
@@ -671,55 +739,29 @@ func menuAddCluster(client *ccp.Client, args []string, Settings *Defaults) (*ccp
 
 	// all settings checked, should have everything ready
 
-	kubernetesversion := getKubeVerFromImage(newclimage)
-	newCluster := &ccp.Cluster{
-		Name: &newclname,
-		Type: ccp.String("vsphere"), // always vSphere
-		// WorkerNodePool: newWorkers,
-		WorkerNodePool: &[]ccp.WorkerNodePool{
-			// first worker node pool
-			ccp.WorkerNodePool{
-				Name:              ccp.String("node-pool"), // default name
-				Size:              ccp.Int64(newclworkers), // default 1 if not defined
-				VCPUs:             ccp.Int64(8),            // Workers always 8
-				Memory:            ccp.Int64(32768),        // Workers always 32 G
-				Template:          &newclimage,             // CCP Image / Template
-				SSHUser:           &Settings.SSHUser,       // from Defaults
-				SSHKey:            &Settings.SSHKey,        // from Defaults
-				KubernetesVersion: &kubernetesversion,      // from Image
-				// Nodes:             &[]ccp.Node{},
-			},
-		},
-		MasterNodePool: &ccp.MasterNodePool{
-			Name:              ccp.String("master-group"),
-			Size:              ccp.Int64(newclmasters),
-			VCPUs:             ccp.Int64(2),
-			Memory:            ccp.Int64(16384),
-			Template:          &newclimage,
-			SSHUser:           &Settings.SSHUser,
-			SSHKey:            &Settings.SSHKey,
-			KubernetesVersion: &kubernetesversion,
-			// Nodes:             &[]ccp.Node{},
-		},
-		Infra: &ccp.Infra{
-			Datastore:  &newcldstore,
-			Datacenter: &newcldc,
-			Networks:   &newclnet, // array of []strings
-			Cluster:    &newclvscluster,
-		},
-		KubernetesVersion:  &kubernetesversion,
-		InfraProviderUUID:  &newclprovideruuid,
-		SubnetUUID:         &newclsubnetuuid,
-		LoadBalancerIPNum:  ccp.Int64(newcllbipnum),
-		IPAllocationMethod: ccp.String("ccpnet"),
-		AWSIamEnabled:      ccp.Bool(false),
-		NetworkPlugin: &ccp.NetworkPlugin{
-			Name: ccp.String("calico"),
-			Details: &ccp.NetworkPluginDetails{
-				PodCIDR: ccp.String(newclpodcidr), // default 192.168.0.0/16
-			},
-		},
+	newCluster, err := ccp.NewClusterBuilder().
+		Name(newclname).
+		Template(newclimage).
+		KubernetesVersion(getKubeVerFromImage(newclimage)).
+		Masters(newclmasters).
+		MasterResources(2, 16384).
+		SSH(Settings.SSHUser, Settings.SSHKey).
+		WorkerPool(ccp.WorkerNodePool{
+			Name:   ccp.String("node-pool"),
+			Size:   ccp.Int64(newclworkers), // default 1 if not defined
+			VCPUs:  ccp.Int64(8),            // Workers always 8
+			Memory: ccp.Int64(32768),        // Workers always 32 G
+		}).
+		Infra(newcldc, newcldstore, newclvscluster, newclnet...).
+		Provider(newclprovideruuid).
+		Subnet(newclsubnetuuid).
+		LoadBalancers(newcllbipnum).
+		NetworkPlugin("calico", newclpodcidr). // default 192.168.0.0/16
+		Build()
+	if err != nil {
+		return nil, err
 	}
+	newCluster.AWSIamEnabled = ccp.Bool(false)
 
 	if jsonout || debuglvl == 3 {
 		prettyPrintJSONCluster(newCluster)
@@ -764,26 +806,36 @@ func menuPatchClusterFromFile(client *ccp.Client, jsonFile string, jsonout bool)
 	return newCluster, nil
 }
 
-func menuAddClusterFromFile(client *ccp.Client, jsonFile string, jsonout bool) (*ccp.Cluster, error) {
-	// --- AddCluster from JSON File
-	// jsonFile := "./cluster.json"
-	// Convert a JSON file to a Cluster struct
-	newCluster, err := client.ConvertJSONToCluster(jsonFile)
+func menuAddClusterFromFile(client *ccp.Client, specFile string, args []string, jsonout bool) (*ccp.Cluster, error) {
+	// --- AddCluster from a JSON or YAML file, which can hold several clusters
+	specFile, options, err := specArgs(specFile, args)
 	if err != nil {
 		fmt.Println("error:", err)
-	} else {
-		fmt.Println("Success")
+		return nil, err
 	}
-
-	fmt.Println("* New cluster name to create: " + *newCluster.Name)
-	createdCluster, err := client.AddCluster(newCluster)
+	specs, err := ccp.LoadClusterSpecWithOptions(specFile, options)
 	if err != nil {
-		fmt.Println("Error from AddCluster:")
-		fmt.Println(err)
+		fmt.Println("error:", err)
 		return nil, err
 	}
-	fmt.Println("* Cluster sent to API: " + *createdCluster.Name)
-	return newCluster, nil
+
+	var createdCluster *ccp.Cluster
+	for i := range specs {
+		newCluster := specs[i].Cluster
+		if newCluster.Name == nil {
+			fmt.Println("error: cluster name is required in", specFile)
+			return nil, errors.New("Cluster name is required")
+		}
+		fmt.Println("* New cluster name to create: " + *newCluster.Name)
+		createdCluster, err = client.AddCluster(&newCluster)
+		if err != nil {
+			fmt.Println("Error from AddCluster:")
+			fmt.Println(err)
+			return nil, err
+		}
+		fmt.Println("* Cluster sent to API: " + *createdCluster.Name)
+	}
+	return createdCluster, nil
 }
 
 func menuGetCluster(client *ccp.Client, clusterName string, jsonout bool) error {
@@ -800,16 +852,170 @@ func menuGetCluster(client *ccp.Client, clusterName string, jsonout bool) error
 	return nil
 }
 
-func menuGetClusterKubeconfig(client *ccp.Client, clusterName string) error {
-	Debug(2, "Getting kubeconfig for"+clusterName)
+func menuGetClusterKubeconfig(client *ccp.Client, clusterName string, args []string, Settings *Defaults) error {
+	var merge, setContext bool
+	var file string
+
+	for _, arg := range args {
+		switch arg {
+		case "--merge":
+			merge = true
+		case "--set-context":
+			setContext = true
+		default:
+			param, value := splitparam(arg)
+			if param == "file" {
+				file = value
+			}
+		}
+	}
+
+	Debug(2, "Getting kubeconfig for "+clusterName)
 	cluster, err := client.GetClusterByName(clusterName)
 	if err != nil {
 		fmt.Println("GetCluster error:", err)
 		return err
 	}
 
-	fmt.Println(*cluster.KubeConfig)
+	if cluster.KubeConfig == nil {
+		fmt.Println("* Cluster", clusterName, "has no kubeconfig yet")
+		return errors.New("Cluster " + clusterName + " has no kubeconfig")
+	}
+
+	if !merge {
+		if setContext {
+			fmt.Println("* --set-context needs --merge")
+		}
+		fmt.Println(*cluster.KubeConfig)
+		return nil
+	}
+
+	path, err := ccp.MergeClusterKubeConfig(cluster, file, setContext)
+	if err != nil {
+		fmt.Println("MergeClusterKubeConfig error:", err)
+		return err
+	}
+	fmt.Println("* Merged kubeconfig for", clusterName, "into", path, "as context", clusterName)
+
+	// remember the file so delcluster can clean it up
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if Settings.KubeConfigFiles == nil {
+		Settings.KubeConfigFiles = map[string]string{}
+	}
+	if Settings.KubeConfigFiles[clusterName] != path {
+		Settings.KubeConfigFiles[clusterName] = path
+		writeDefaults(Settings)
+	}
+	if setContext {
+		fmt.Println("* Current context set to", clusterName)
+	}
+
+	return nil
+}
+
+// clusterCert is one line of the certs command
+type clusterCert struct {
+	Cluster  string    `json:"cluster"`
+	Server   string    `json:"server,omitempty"`
+	Subject  string    `json:"client_subject,omitempty"`
+	NotAfter time.Time `json:"client_not_after"`
+	State    string    `json:"state"`
+	Error    string    `json:"error,omitempty"`
+}
+
+func menuGetCerts(client *ccp.Client, args []string, jsonout bool) error {
+	warnDays := 30
+
+	for _, arg := range args {
+		param, value := splitparam(arg)
+		switch param {
+		case "warn":
+			warnDays = strtoint(value)
+		case "json", "debug":
+			// global flags
+		default:
+			fmt.Println("Error, flag ", arg, " unknown")
+		}
+	}
+	warn := time.Duration(warnDays) * 24 * time.Hour
+
+	clusters, err := client.GetClusters()
+	if err != nil {
+		fmt.Println("GetClusters error:", err)
+		return err
+	}
+
+	var certs []clusterCert
+	for _, cluster := range clusters {
+		cert := clusterCert{Cluster: "<unnamed>"}
+		if cluster.Name != nil {
+			cert.Cluster = *cluster.Name
+		}
+
+		if cluster.KubeConfig == nil {
+			cert.State = "NOKUBECONFIG"
+			certs = append(certs, cert)
+			continue
+		}
+
+		// only the inline certificates are read from a kubeconfig served by CCP, never files it names
+		info, err := kubeconfig.InspectInline([]byte(*cluster.KubeConfig))
+		switch {
+		case err != nil:
+			cert.State = "ERROR"
+			cert.Error = err.Error()
+		case info.ClientCertificate == nil:
+			cert.Server = info.Server
+			cert.State = "NOCLIENTCERT"
+		default:
+			cert.Server = info.Server
+			cert.Subject = info.ClientSubject
+			cert.NotAfter = info.ClientNotAfter
+			cert.State = "OK"
+			if info.Expired() {
+				cert.State = "EXPIRED"
+			} else if info.ExpiresWithin(warn) {
+				cert.State = "WARNING"
+			}
+		}
+		certs = append(certs, cert)
+	}
+
+	// soonest expiry first, clusters without a certificate last
+	sort.SliceStable(certs, func(i, j int) bool {
+		if certs[i].NotAfter.IsZero() != certs[j].NotAfter.IsZero() {
+			return !certs[i].NotAfter.IsZero()
+		}
+		return certs[i].NotAfter.Before(certs[j].NotAfter)
+	})
+
+	if jsonout {
+		jsonBody, err := json.Marshal(certs)
+		if err != nil {
+			fmt.Println("JSON Marshal error:", err)
+			return err
+		}
+		prettyPrintJSONString(string(jsonBody))
+		return nil
+	}
 
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tEXPIRES\tDAYS\tSTATE\tSUBJECT")
+	for _, cert := range certs {
+		expires, days := "-", "-"
+		if !cert.NotAfter.IsZero() {
+			expires = cert.NotAfter.Format("2006-01-02")
+			days = inttostr(int(time.Until(cert.NotAfter).Hours() / 24))
+		}
+		state := cert.State
+		if cert.Error != "" {
+			state = state + " " + cert.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", cert.Cluster, expires, days, state, cert.Subject)
+	}
+	w.Flush()
 	return nil
 }
 
@@ -831,14 +1037,7 @@ func menuGetClusterAddon(client *ccp.Client, clusterName string, jsonout bool) e
 		prettyPrintJSONClusterAddonCatalogue(catalogue)
 	}
 	fmt.Println("Addon available:")
-	// this is ugly, have to revisit this
-	fmt.Println("* kubeflow: ", catalogue.CcpKubeflow.Name, "Description:", catalogue.CcpKubeflow.Description)
-	fmt.Println("* dashboard: ", catalogue.CcpKubernetesDashboard.Name, "Description:", catalogue.CcpKubernetesDashboard.Description)
-	fmt.Println("* harbor: ", catalogue.CcpHarborOperator.Name, "Description:", catalogue.CcpHarborOperator.Description)
-	fmt.Println("* logging: ", catalogue.CcpEfk.Name, "", "Description:", catalogue.CcpEfk.Description)
-	fmt.Println("* monitoring: ", catalogue.CcpMonitor.Name, "Description:", catalogue.CcpMonitor.Description)
-	fmt.Println("* istio: ", catalogue.CcpIstioOperator.Name, "Description:", catalogue.CcpIstioOperator.Description)
-	fmt.Println("* hx-csi: ", catalogue.CcpHxcsi.Name, "Description:", catalogue.CcpHxcsi.Description)
+	printAddonsCatalogue(catalogue)
 	fmt.Println("")
 
 	// list installed Addon
@@ -880,86 +1079,321 @@ func menuGetClusterAddonCatalogue(client *ccp.Client, clusterName string, jsonou
 		prettyPrintJSONClusterAddonCatalogue(addon)
 	}
 	fmt.Println("Addon available:")
-	fmt.Printf("%v\n", addon)
+	printAddonsCatalogue(addon)
 	return nil
 }
 
-func menuInstallClusterAddonNew(client *ccp.Client, clusterName string, addon string, jsonout bool) error {
+// printAddonsCatalogue lists every addon the server offers, dependencies indented under the addon they need
+func printAddonsCatalogue(catalogue ccp.AddonsCatalogue) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDISPLAY NAME\tCONFLICTS\tDESCRIPTION")
+	var printEntries func(catalogue ccp.AddonsCatalogue, indent string)
+	printEntries = func(catalogue ccp.AddonsCatalogue, indent string) {
+		for _, key := range catalogue.Keys() {
+			entry := catalogue[key]
+			conflicts := "-"
+			if len(entry.Conflicts) > 0 {
+				conflicts = strings.Join(entry.Conflicts, ",")
+			}
+			fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\n", indent, entry.Name, entry.DisplayName, conflicts, entry.Description)
+			printEntries(entry.Dependencies, indent+"  ")
+		}
+	}
+	printEntries(catalogue, "")
+	w.Flush()
+}
+
+func menuInstallChart(client *ccp.Client, clusterName string, args []string, jsonout bool) error {
 	cluster, err := client.GetClusterByName(clusterName)
 	if err != nil {
 		fmt.Println("GetCluster error:", err)
 		return err
 	}
 
-	// case "kubernetes-dashboard":
-	// case "ccp-efk":
-	// case "ccp-monitor":
-	// case "istio":
-	// case "harbor":
-	// case "ccp-kubeflow":
+	var chart ccp.ChartSpec
+	for i := 0; i < len(args); i++ {
+		param, value := splitparam(args[i])
+		if args[i] == "-f" && i+1 < len(args) {
+			param, value = "file", args[i+1]
+			i++
+		}
+		switch param {
+		case "name":
+			chart.Name = value
+		case "url":
+			chart.URL = value
+		case "namespace":
+			chart.Namespace = value
+		case "description":
+			chart.Description = value
+		case "file":
+			chart.Values, err = ioutil.ReadFile(value)
+			if err != nil {
+				return err
+			}
+		case "json", "debug":
+			// global flags
+		default:
+			return errors.New("Unknown option " + args[i])
+		}
+	}
 
-	fmt.Println("* Installing addon " + addon)
-	switch addon {
-	case "monitoring", "ccp-monitor":
-		err = client.InstallAddon(*cluster.UUID, "ccp-monitor")
+	if !jsonout {
+		fmt.Println("* Installing chart " + chart.Name + " from " + chart.URL)
+	}
+	err = client.InstallCustomChart(context.Background(), *cluster.UUID, chart)
+	if err != nil {
+		return err
+	}
+	if jsonout {
+		addon, err := client.GetClusterAddon(*cluster.UUID, chart.Name)
 		if err != nil {
 			return err
 		}
-		fmt.Println("* Installed " + addon + " addon. Check status with getaddon")
-	case "logging", "ccp-efk":
-		err = client.InstallAddon(*cluster.UUID, "ccp-efk")
+		jsonBody, err := json.Marshal(addon)
 		if err != nil {
 			return err
 		}
-		fmt.Println("* Installed " + addon + " addon. Check status with getaddon")
-	case "harbor":
-		err = client.InstallAddon(*cluster.UUID, "harbor")
-		if err != nil {
-			return err
+		prettyPrintJSONString(string(jsonBody))
+		return nil
+	}
+	fmt.Println("* Installed chart " + chart.Name + ". Check status with getaddons, remove it with deladdon")
+	return nil
+}
+
+func menuGetAddonEndpoints(client *ccp.Client, args []string, jsonout bool) error {
+	var clusterName string
+	open := false
+	for _, arg := range args {
+		param, _ := splitparam(arg)
+		switch {
+		case arg == "--open":
+			open = true
+		case param == "json" || param == "debug":
+			// global flags
+		default:
+			if clusterName != "" {
+				return errors.New("Unknown option " + arg)
+			}
+			clusterName = arg
 		}
-		fmt.Println("* Installed " + addon + " addon. Check status with getaddon")
-	case "hx-csi":
-		err = client.InstallAddon(*cluster.UUID, "hx-csi")
+	}
+	if clusterName == "" {
+		return errors.New("endpoints <clustername> [--open]")
+	}
+
+	cluster, err := client.GetClusterByName(clusterName)
+	if err != nil {
+		fmt.Println("GetCluster error:", err)
+		return err
+	}
+
+	endpoints, err := client.GetAddonEndpoints(*cluster.UUID)
+	if err != nil {
+		return err
+	}
+
+	if jsonout {
+		jsonBody, err := json.Marshal(endpoints)
 		if err != nil {
 			return err
 		}
-		fmt.Println("* Installed " + addon + " addon. Check status with getaddon")
-	case "kubeflow", "ccp-kubeflow":
-		err = client.InstallAddon(*cluster.UUID, "ccp-kubeflow")
+		prettyPrintJSONString(string(jsonBody))
+		return nil
+	}
+
+	// --open prints just the links, one per line, for a terminal that opens them on click
+	if open {
+		for _, endpoint := range endpoints {
+			if endpoint.ServiceURL == "" {
+				continue
+			}
+			name := endpoint.DisplayName
+			if name == "" {
+				name = endpoint.Name
+			}
+			fmt.Println(name + ": " + endpointLink(cluster, endpoint.ServiceURL))
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDON\tNAMESPACE\tSTATUS\tURL")
+	for _, endpoint := range endpoints {
+		link := "-"
+		if endpoint.ServiceURL != "" {
+			link = endpoint.ServiceURL
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", endpoint.Name, endpoint.Namespace, endpoint.Status, link)
+	}
+	w.Flush()
+	return nil
+}
+
+// endpointLink makes a service URL clickable: a path is put on the cluster master VIP and a URL without a
+// scheme gets https://
+func endpointLink(cluster *ccp.Cluster, serviceURL string) string {
+	if strings.Contains(serviceURL, "://") {
+		return serviceURL
+	}
+	if strings.HasPrefix(serviceURL, "/") && cluster.MasterVIP != nil {
+		return "https://" + *cluster.MasterVIP + serviceURL
+	}
+	return "https://" + serviceURL
+}
+
+func menuInstallClusterAddonNew(client *ccp.Client, clusterName string, addon string, args []string, jsonout bool) error {
+	cluster, err := client.GetClusterByName(clusterName)
+	if err != nil {
+		fmt.Println("GetCluster error:", err)
+		return err
+	}
+
+	// addons come from the cluster catalogue unless a definition file is given
+	var registry *ccp.AddonRegistry
+	var values []byte
+	for i := 0; i < len(args); i++ {
+		param, value := splitparam(args[i])
+		if args[i] == "-f" && i+1 < len(args) {
+			param, value = "file", args[i+1]
+			i++
+		}
+		switch param {
+		case "addons":
+			registry, err = ccp.LoadAddonRegistry(value)
+		case "file":
+			values, err = ioutil.ReadFile(value)
+		case "json", "debug":
+			// global flags
+		default:
+			return errors.New("Unknown option " + args[i])
+		}
 		if err != nil {
 			return err
 		}
-		fmt.Println("* Installed " + addon + " addon. Check status with getaddon")
-	case "dashboard", "kubernetes-dashboard":
-		err = client.InstallAddon(*cluster.UUID, "kubernetes-dashboard")
+	}
+	if registry == nil {
+		registry, err = client.GetAddonRegistry(*cluster.UUID)
 		if err != nil {
 			return err
 		}
-		fmt.Println("* Installed " + addon + " addon. Check status with getaddon")
-	case "istio":
-		err = client.InstallAddon(*cluster.UUID, "istio")
+	}
+
+	// a comma separated list installs the addons together, e.g. monitoring,logging,dashboard
+	addons := strings.Split(addon, ",")
+	if len(addons) > 1 {
+		if values != nil {
+			return errors.New("Values can only be given when installing one addon")
+		}
+		return installClusterAddons(client, *cluster.UUID, registry, addons, jsonout)
+	}
+
+	if !jsonout {
+		fmt.Println("* Installing addon " + addon)
+	}
+	err = client.InstallAddonFromRegistry(context.Background(), *cluster.UUID, registry, addon, values)
+	if err != nil {
+		return err
+	}
+	if !jsonout {
+		fmt.Println("* Installed " + addon + " addon. Check status with getaddons")
+	}
+	return nil
+}
+
+func installClusterAddons(client *ccp.Client, clusterUUID string, registry *ccp.AddonRegistry, addons []string, jsonout bool) error {
+	if !jsonout {
+		fmt.Println("* Installing addons " + strings.Join(addons, ", "))
+	}
+	results, err := client.InstallAddonsFromRegistry(context.Background(), clusterUUID, registry, addons)
+	if results == nil {
+		return err
+	}
+
+	if jsonout {
+		jsonBody, jsonErr := json.Marshal(results)
+		if jsonErr != nil {
+			return jsonErr
+		}
+		prettyPrintJSONString(string(jsonBody))
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDON\tRESULT\tERROR")
+	for _, result := range results {
+		message := "-"
+		if result.Error != "" {
+			message = result.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.Name, result.Result, message)
+	}
+	w.Flush()
+	return err
+}
+
+func menuUpdateClusterAddon(client *ccp.Client, command string, clusterName string, addon string, args []string, jsonout bool) error {
+	cluster, err := client.GetClusterByName(clusterName)
+	if err != nil {
+		fmt.Println("GetCluster error:", err)
+		return err
+	}
+
+	var version string
+	var values []byte
+	for i := 0; i < len(args); i++ {
+		param, value := splitparam(args[i])
+		if args[i] == "-f" && i+1 < len(args) {
+			param, value = "file", args[i+1]
+			i++
+		}
+		switch param {
+		case "version":
+			version = value
+		case "file":
+			values, err = ioutil.ReadFile(value)
+			if err != nil {
+				return err
+			}
+		case "json", "debug":
+			// global flags
+		default:
+			return errors.New("Unknown option " + args[i])
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*ccp.AddonInstallTimeout)
+	defer cancel()
+
+	var update *ccp.AddonUpdate
+	if command == "reconfigureaddon" {
+		if values == nil {
+			return errors.New("reconfigureaddon needs the values, -f values.yaml")
+		}
+		update, err = client.ReconfigureAddon(ctx, *cluster.UUID, addon, values)
+	} else {
+		update, err = client.UpgradeAddon(ctx, *cluster.UUID, addon, version)
+	}
+	if err != nil {
+		return err
+	}
+
+	if jsonout {
+		jsonBody, err := json.Marshal(update)
 		if err != nil {
 			return err
 		}
-		fmt.Println("* Installed " + addon + " addon. Check status with getaddon")
-	// case "all":
-	// 	err = client.InstallAddonMonitoring(*cluster.UUID)
-	// 	fmt.Println("* Installing monitoring Addon. Check status with getaddon")
-	// 	err = client.InstallAddonLogging(*cluster.UUID)
-	// 	fmt.Println("* Installing logging Addon. Check status with getaddon")
-	// 	err = client.InstallAddonIstio(*cluster.UUID)
-	// 	fmt.Println("* Installing istio Addon. Check status with getaddon")
-	// 	err = client.InstallAddonHarbor(*cluster.UUID)
-	// 	fmt.Println("* Installing harbor Addon. Check status with getaddon")
-	// 	err = client.InstallAddonHXCSI(*cluster.UUID)
-	// 	fmt.Println("* Installing hx-csi Addon. Check status with getaddon")
-	// 	err = client.InstallAddonKubeflow(*cluster.UUID)
-	// 	fmt.Println("* Installing kubeflow Addon. Check status with getaddon")
-	// 	err = client.InstallAddonDashboard(*cluster.UUID)
-	// 	fmt.Println("* Installing dashboard Addon. Check status with getaddon")
-
-	default:
-		return errors.New("Unknown addon:" + addon + ".  Valid options are: monitoring, logging, istio, harbor, hx-csi, kubeflow, dashboard")
+		prettyPrintJSONString(string(jsonBody))
+		return nil
+	}
+	if !update.Changed {
+		fmt.Println("* Addon " + update.Name + " is up to date, version " + update.OldVersion)
+		return nil
+	}
+	fmt.Println("* Addon " + update.Name + " installed again, " + update.Reason)
+	fmt.Println("  version " + update.OldVersion + " -> " + update.NewVersion + ", override hash " + update.OldOverrideHash + " -> " + update.NewOverrideHash)
+	if len(update.Reinstalled) > 0 {
+		fmt.Println("  addons which need it were installed again: " + strings.Join(update.Reinstalled, ", "))
 	}
 	return nil
 }
@@ -1124,83 +1558,120 @@ func menuDelClusterAddonNew(client *ccp.Client, clusterName string, addon string
 		return err
 	}
 
-	switch addon {
-	case "monitoring":
-		err = client.DeleteAddon(*cluster.UUID, "ccp-monitor")
-		if err != nil {
-			return err
-		}
-		fmt.Println("* Deleting monitoring Addon. Check status with getaddon")
-	case "logging":
-		err = client.DeleteAddon(*cluster.UUID, "ccp-efk")
-		if err != nil {
-			return err
-		}
-		fmt.Println("* Deleting logging Addon. Check status with getaddon")
-	case "istio":
-		err = client.DeleteAddon(*cluster.UUID, "istio")
-		if err != nil {
-			return err
+	registry, err := client.GetAddonRegistry(*cluster.UUID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ccp.AddonDeleteTimeout)
+	defer cancel()
+
+	fmt.Println("* Deleting addon " + addon)
+	result, err := client.DeleteAddonFromRegistry(ctx, *cluster.UUID, registry, addon)
+	if result != nil {
+		if jsonout {
+			jsonBody, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			prettyPrintJSONString(string(jsonBody))
+		} else {
+			for _, name := range result.Deleted {
+				fmt.Println("* Deleted addon " + name)
+			}
+			for _, name := range result.Leftover {
+				fmt.Println("* Still installed: " + name)
+			}
 		}
-		fmt.Println("* Deleting istio Addon. Check status with getaddon")
-	case "harbor":
-		err = client.DeleteAddon(*cluster.UUID, "harbor")
-		if err != nil {
-			return err
+	}
+	return err
+}
+
+func menuDelCluster(client *ccp.Client, clusterName string, args []string, Settings *Defaults) error {
+	var yes, force bool
+	var file string
+
+	for _, arg := range args {
+		switch arg {
+		case "--yes", "-y":
+			yes = true
+		case "--force":
+			force = true
+		default:
+			param, value := splitparam(arg)
+			if param == "file" {
+				file = value
+			}
 		}
-		fmt.Println("* Deleting harbor Addon. Check status with getaddon")
-	case "hx-csi":
-		err = client.DeleteAddon(*cluster.UUID, "hx-csi")
-		if err != nil {
-			return err
-		}
-		fmt.Println("* Deleting hx-csi Addon. Check status with getaddon")
-	case "kubeflow":
-		err = client.DeleteAddon(*cluster.UUID, "kubeflow")
-		if err != nil {
-			return err
-		}
-		fmt.Println("* Deleting kubeflow Addon. Check status with getaddon")
-	case "dashboard":
-		err = client.DeleteAddon(*cluster.UUID, "kubernetes-dashboard")
-		if err != nil {
-			return err
-		}
-		fmt.Println("* Deleting dashboard Addon. Check status with getaddon")
-	// case "all":
-	// 	err = client.DeleteAddonMonitor(*cluster.UUID)
-	// 	fmt.Println("* Deleting monitoring Addon. Check status with getaddon")
-	// 	err = client.DeleteAddonLogging(*cluster.UUID)
-	// 	fmt.Println("* Deleting logging Addon. Check status with getaddon")
-	// 	err = client.DeleteAddonIstio(*cluster.UUID)
-	// 	fmt.Println("* Deleting istio Addon. Check status with getaddon")
-	// 	err = client.DeleteAddonHarbor(*cluster.UUID)
-	// 	fmt.Println("* Deleting harbor Addon. Check status with getaddon")
-	// 	err = client.DeleteAddonHXCSI(*cluster.UUID)
-	// 	fmt.Println("* Deleting hx-csi Addon. Check status with getaddon")
-	// 	err = client.DeleteAddonKubeflow(*cluster.UUID)
-	// 	fmt.Println("* Deleting kubeflow Addon. Check status with getaddon")
-	// 	err = client.DeleteAddonDashboard(*cluster.UUID)
-	// 	fmt.Println("* Deleting dashboard Addon. Check status with getaddon")
-	default:
-		return errors.New("Unknown addon:" + addon + ".  Valid options are: monitoring, logging, istio, harbor, hx-csi, kubeflow, dashboard")
 	}
-	return nil
-}
 
-func menuDelCluster(client *ccp.Client, clusterName string) error {
 	cluster, err := client.GetClusterByName(clusterName)
 	if err != nil {
 		fmt.Println("DeleteCluster error:", err)
 		return err
 	}
 
-	err = client.DeleteCluster(*cluster.UUID)
+	if client.IsClusterProtected(cluster) {
+		if !force {
+			fmt.Println("* Cluster", clusterName, "is protected from deletion, use --force to delete it anyway")
+			return ccp.ErrClusterProtected
+		}
+		fmt.Println("* WARNING: cluster", clusterName, "is protected, deleting because --force was given")
+	}
+
+	if !yes {
+		fmt.Print("* Type the cluster name to confirm deletion of ", clusterName, ": ")
+		reader := bufio.NewReader(os.Stdin)
+		typed, _ := reader.ReadString('\n')
+		if strings.TrimSpace(typed) != clusterName {
+			fmt.Println("* Cluster name did not match, not deleting")
+			return errors.New("Deletion of cluster " + clusterName + " not confirmed")
+		}
+	}
+
+	if force {
+		err = client.DeleteProtectedCluster(*cluster.UUID)
+	} else {
+		err = client.DeleteCluster(*cluster.UUID)
+	}
 	if err != nil {
 		fmt.Println("DeleteCluster error:", err)
 		return err
 	}
 	fmt.Println("Cluster ", clusterName, " deleted")
+
+	// clean up the context only from the file getkubeconf --merge wrote it to and from file=, a context of the
+	// same name in any other kubeconfig may be hand made or for another CCP, so it is only reported
+	paths := []string{}
+	if file != "" {
+		paths = append(paths, file)
+	}
+	if merged, ok := Settings.KubeConfigFiles[clusterName]; ok {
+		paths = append(paths, merged)
+	}
+
+	removedFrom, err := ccp.RemoveClusterKubeConfigs(clusterName, paths)
+	for _, path := range removedFrom {
+		fmt.Println("* Removed kubeconfig context", clusterName, "from", path)
+	}
+	if err != nil {
+		fmt.Println("* Could not remove kubeconfig context:", err)
+	}
+	if _, ok := Settings.KubeConfigFiles[clusterName]; ok && err == nil {
+		delete(Settings.KubeConfigFiles, clusterName)
+		writeDefaults(Settings)
+	}
+
+	removed := map[string]bool{}
+	for _, path := range paths {
+		removed[path] = true
+	}
+	found, _ := ccp.FindClusterKubeConfigs(clusterName, nil)
+	for _, path := range found {
+		if !removed[path] {
+			fmt.Println("* Kubeconfig context", clusterName, "is still in", path, "- not removed as getkubeconf --merge did not write it")
+		}
+	}
 	return nil
 }
 
@@ -1258,7 +1729,33 @@ func menuGetSubnet(client *ccp.Client, subnetName string, jsonout bool) {
 	}
 }
 
-func menuScaleCluster(client *ccp.Client, clusterName string, workers int, jsonout bool) error {
+func menuScaleCluster(client *ccp.Client, clusterName string, args []string, jsonout bool) error {
+	var workers int
+	var workerpoolname string
+	var wait bool
+
+	for _, arg := range args {
+		if arg == "--wait" {
+			wait = true
+			continue
+		}
+		param, value := splitparam(arg)
+		switch param {
+		case "workers":
+			workers = strtoint(value)
+		case "pool":
+			workerpoolname = value
+		case "json", "debug":
+			// global flags
+		default:
+			fmt.Println("Error, flag ", arg, " unknown")
+		}
+	}
+
+	if workers < 1 {
+		return errors.New("Error: workers must be 1 or more")
+	}
+
 	cluster, err := client.GetClusterByName(clusterName)
 	if err != nil {
 		fmt.Println("ScaleCluster GetClusterByName error:", err)
@@ -1266,16 +1763,625 @@ func menuScaleCluster(client *ccp.Client, clusterName string, workers int, jsono
 	}
 
 	Debug(2, "Got cluster "+*cluster.Name)
-	workerpoolname := "node-group"
 
-	scalecluster, err := client.ScaleCluster(*cluster.UUID, workerpoolname, workers)
+	// if no pool is given use the only pool in the cluster
+	if workerpoolname == "" {
+		workerpoolname, err = client.DiscoverNodePool(*cluster.UUID)
+		if err != nil {
+			fmt.Println("ScaleCluster error: ", err)
+			return err
+		}
+		Debug(2, "Discovered worker pool "+workerpoolname)
+	}
+
+	if wait {
+		fmt.Println("* Scaling worker pool", workerpoolname, "and waiting for nodes to be ready")
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		_, err = client.ScaleClusterAndWait(ctx, *cluster.UUID, workerpoolname, workers)
+	} else {
+		_, err = client.ScaleCluster(*cluster.UUID, workerpoolname, workers)
+	}
 	if err != nil {
 		fmt.Println("ScaleCluster error: ", err)
 		return err
 	}
 
-	fmt.Println("Cluster worker pool: ", *scalecluster.Name, " scaled to size "+inttostr(workers))
+	fmt.Println("Cluster worker pool: ", workerpoolname, " scaled to size "+inttostr(workers))
+
+	return nil
+}
+
+func menuVerifyCluster(client *ccp.Client, clusterName string, jsonout bool) error {
+	cluster, err := client.GetClusterByName(clusterName)
+	if err != nil {
+		fmt.Println("GetCluster error:", err)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	report, err := ccp.VerifyCluster(ctx, cluster)
+	if report == nil {
+		fmt.Println("VerifyCluster error:", err)
+		return err
+	}
+
+	if jsonout {
+		jsonBody, err := json.Marshal(report)
+		if err != nil {
+			fmt.Println("JSON Marshal error:", err)
+			return err
+		}
+		prettyPrintJSONString(string(jsonBody))
+	} else {
+		fmt.Println("* Cluster:", report.Cluster, "API server:", report.Server)
+		fmt.Println("* Version:", report.Version, "Ready:", report.Ready)
+		fmt.Println("* Nodes registered:", report.RegisteredNodes, "ready:", report.ReadyNodes, "expected:", report.ExpectedNodes)
+		for _, e := range report.Errors {
+			fmt.Println("* FAILED:", e)
+		}
+		if report.OK() {
+			fmt.Println("* Cluster", clusterName, "verified")
+		}
+	}
+	return err
+}
+
+// specArgs reads the cluster spec file and the options for reading it: -f file or file=path, --strict,
+// values=file and --set name=value. Environment variables fill in any other ${name} variables
+func specArgs(path string, args []string) (string, ccp.SpecOptions, error) {
+	options := ccp.SpecOptions{Values: ccp.SpecValues{}, Env: true}
+	var valuesFile string
+	var sets []string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--strict":
+			options.Strict = true
+		case "-f", "--file", "--set", "--values":
+			if i+1 >= len(args) {
+				return "", options, errors.New(args[i] + " needs a value")
+			}
+			switch args[i] {
+			case "--set":
+				sets = append(sets, args[i+1])
+			case "--values":
+				valuesFile = args[i+1]
+			default:
+				path = args[i+1]
+			}
+			i++
+		default:
+			key, value := splitparam(args[i])
+			switch key {
+			case "file":
+				path = value
+			case "values":
+				valuesFile = value
+			}
+		}
+	}
+
+	if valuesFile != "" {
+		values, err := ccp.LoadSpecValues(valuesFile)
+		if err != nil {
+			return "", options, err
+		}
+		options.Values = values
+	}
+	// --set wins over the values file
+	for _, set := range sets {
+		if err := options.Values.Set(set); err != nil {
+			return "", options, err
+		}
+	}
+	return path, options, nil
+}
+
+func menuRender(args []string) error {
+	path, options, err := specArgs("", args)
+	if err != nil {
+		fmt.Println("error:", err)
+		return err
+	}
+	if path == "" {
+		fmt.Println("render -f <file|directory> [values=file] [--set name=value] [--strict]")
+		return errors.New("No cluster spec file given")
+	}
+
+	specs, err := ccp.ReadClusterSpecs(path, options)
+	if err != nil {
+		fmt.Println("error:", err)
+		return err
+	}
+
+	for _, spec := range specs {
+		jsonBody, err := json.Marshal(spec.Cluster)
+		if err != nil {
+			fmt.Println("JSON Marshal error:", err)
+			return err
+		}
+		prettyPrintJSONString(string(jsonBody))
+		if spec.Addons != nil {
+			fmt.Println("* Addons:", strings.Join(*spec.Addons, ", "))
+		}
+	}
+	return nil
+}
+
+func menuApply(client *ccp.Client, args []string, jsonout bool) error {
+	path, options, err := specArgs("", args)
+	if err != nil {
+		fmt.Println("error:", err)
+		return err
+	}
+	if path == "" {
+		fmt.Println("apply -f <file|directory> [values=file] [--set name=value] [--strict]")
+		return errors.New("No cluster spec file given")
+	}
+
+	specs, err := ccp.ReadClusterSpecs(path, options)
+	if err != nil {
+		fmt.Println("ReadClusterSpecs error:", err)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	var allActions []ccp.ApplyAction
+	for i := range specs {
+		actions, err := client.ApplyClusterSpec(ctx, &specs[i])
+		allActions = append(allActions, actions...)
+		if !jsonout {
+			for _, action := range actions {
+				fmt.Println("*", action.Cluster+":", action.Action, action.Detail)
+			}
+			if err == nil && len(actions) == 0 {
+				fmt.Println("*", *specs[i].Name+":", "up to date")
+			}
+		}
+		if err != nil {
+			fmt.Println("ApplyClusterSpec error:", *specs[i].Name, err)
+			return err
+		}
+	}
 
+	if jsonout {
+		jsonBody, err := json.Marshal(allActions)
+		if err != nil {
+			fmt.Println("JSON Marshal error:", err)
+			return err
+		}
+		prettyPrintJSONString(string(jsonBody))
+	}
+	return nil
+}
+
+func menuDiff(client *ccp.Client, args []string, jsonout bool) error {
+	path, options, err := specArgs("", args)
+	if err != nil {
+		fmt.Println("error:", err)
+		return err
+	}
+	if path == "" {
+		fmt.Println("diff -f <file|directory> [values=file] [--set name=value] [--strict]")
+		return errors.New("No cluster spec file given")
+	}
+
+	specs, err := ccp.ReadClusterSpecs(path, options)
+	if err != nil {
+		fmt.Println("ReadClusterSpecs error:", err)
+		return err
+	}
+
+	var diffs []*ccp.ClusterDiff
+	for i := range specs {
+		diff, err := client.DiffClusterSpec(&specs[i].Cluster)
+		if err != nil {
+			fmt.Println("DiffClusterSpec error:", *specs[i].Name, err)
+			return err
+		}
+		diffs = append(diffs, diff)
+	}
+
+	if jsonout {
+		jsonBody, err := json.Marshal(diffs)
+		if err != nil {
+			fmt.Println("JSON Marshal error:", err)
+			return err
+		}
+		prettyPrintJSONString(string(jsonBody))
+		return nil
+	}
+
+	// Terraform plan style: + create, ~ update in place, -/+ replace
+	var create, update, replace int
+	for _, diff := range diffs {
+		switch {
+		case diff.Create:
+			create++
+			fmt.Printf("  + cluster %q will be created\n\n", diff.Cluster)
+			continue
+		case diff.Empty():
+			fmt.Printf("    cluster %q is up to date\n", diff.Cluster)
+			for _, change := range diff.Changes {
+				fmt.Printf("      # %s: not in the spec, left alone\n", change.Path)
+			}
+			fmt.Println()
+			continue
+		case diff.RequiresRecreate():
+			replace++
+			fmt.Printf("-/+ cluster %q must be replaced\n", diff.Cluster)
+		default:
+			update++
+			fmt.Printf("  ~ cluster %q will be updated in-place\n", diff.Cluster)
+		}
+
+		for _, change := range diff.Changes {
+			switch {
+			case change.Kind == ccp.ChangeUnmanaged:
+				fmt.Printf("      # %s: not in the spec, left alone\n", change.Path)
+			case change.Old == nil && change.Pool != "" && change.Kind == ccp.ChangeInPlace:
+				fmt.Printf("      + %s: %s\n", change.Path, diffValueString(change.New))
+			case change.New == nil:
+				fmt.Printf("      - %s: %s\n", change.Path, diffValueString(change.Old))
+			case change.Kind == ccp.ChangeRecreate:
+				fmt.Printf("      ~ %s: %s -> %s # forces replacement\n", change.Path, diffValueString(change.Old), diffValueString(change.New))
+			case change.Kind == ccp.ChangeScale:
+				fmt.Printf("      ~ %s: %s -> %s # scale\n", change.Path, diffValueString(change.Old), diffValueString(change.New))
+			case change.Kind == ccp.ChangeUpgrade:
+				fmt.Printf("      ~ %s: %s -> %s # upgrade\n", change.Path, diffValueString(change.Old), diffValueString(change.New))
+			default:
+				fmt.Printf("      ~ %s: %s -> %s\n", change.Path, diffValueString(change.Old), diffValueString(change.New))
+			}
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Plan: %d to create, %d to update, %d to replace.\n", create, update, replace)
+	return nil
+}
+
+// diffValueString formats a value from a cluster diff as JSON, nil shows as (none)
+func diffValueString(value interface{}) string {
+	if value == nil {
+		return "(none)"
+	}
+	jsonBody, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(jsonBody)
+}
+
+func menuExportCluster(client *ccp.Client, clusterName string, args []string) error {
+	var file string
+	keepSSHKeys := false
+
+	for _, arg := range args {
+		switch arg {
+		case "--ssh-keys":
+			keepSSHKeys = true
+		default:
+			key, value := splitparam(arg)
+			if key == "file" {
+				file = value
+			}
+		}
+	}
+
+	cluster, err := client.GetClusterByName(clusterName)
+	if err != nil {
+		fmt.Println("GetCluster error:", err)
+		return err
+	}
+	cluster, err = client.GetClusterByUUID(*cluster.UUID)
+	if err != nil {
+		fmt.Println("GetCluster error:", err)
+		return err
+	}
+
+	jsonBody, err := ccp.ExportClusterSpecJSON(cluster, keepSSHKeys)
+	if err != nil {
+		fmt.Println("ExportClusterSpec error:", err)
+		return err
+	}
+
+	if file == "" {
+		fmt.Println(string(jsonBody))
+		return nil
+	}
+	err = ioutil.WriteFile(file, append(jsonBody, '\n'), 0600)
+	if err != nil {
+		fmt.Println("Write error:", err)
+		return err
+	}
+	fmt.Println("* Cluster", clusterName, "exported to", file)
+	return nil
+}
+
+func menuUpgradeCluster(client *ccp.Client, clusterName string, args []string) error {
+	var image string
+
+	for _, arg := range args {
+		param, value := splitparam(arg)
+		switch param {
+		case "image":
+			image = value
+		case "json", "debug":
+			// global flags
+		default:
+			fmt.Println("Error, flag ", arg, " unknown")
+		}
+	}
+
+	if image == "" {
+		return errors.New("Error: image is blank, exiting")
+	}
+
+	cluster, err := client.GetClusterByName(clusterName)
+	if err != nil {
+		fmt.Println("GetCluster error:", err)
+		return err
+	}
+
+	fmt.Println("* Upgrading cluster", clusterName, "to image", image)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	_, err = client.UpgradeClusterWithProgress(ctx, *cluster.UUID, image, func(status ccp.UpgradeStatus) {
+		fmt.Println("* Upgrade", status.Stage, status.State+":", status.Message)
+	})
+	if err != nil {
+		fmt.Println("UpgradeCluster error:", err)
+		return err
+	}
+	fmt.Println("* Cluster", clusterName, "upgraded")
+	return nil
+}
+
+func menuUpgradePlan(client *ccp.Client, args []string, jsonout bool) error {
+	var image string
+	var names []string
+
+	for _, arg := range args {
+		param, value := splitparam(arg)
+		switch param {
+		case "image":
+			image = value
+		case "clusters":
+			names = strings.Split(value, ",")
+		case "json", "debug":
+			// global flags
+		default:
+			fmt.Println("Error, flag ", arg, " unknown")
+		}
+	}
+
+	if image == "" {
+		return errors.New("Error: image is blank, exiting")
+	}
+
+	clusters, err := client.GetClusters()
+	if err != nil {
+		fmt.Println("GetClusters error:", err)
+		return err
+	}
+
+	if len(names) > 0 {
+		var selected []ccp.Cluster
+		for _, name := range names {
+			found := false
+			for _, cluster := range clusters {
+				if cluster.Name != nil && *cluster.Name == name {
+					selected = append(selected, cluster)
+					found = true
+				}
+			}
+			if !found {
+				return errors.New("Cannot find cluster " + name)
+			}
+		}
+		clusters = selected
+	}
+
+	plans, err := client.PlanUpgrade(clusters, image)
+	if err != nil {
+		fmt.Println("PlanUpgrade error:", err)
+		return err
+	}
+
+	if jsonout {
+		jsonBody, err := json.Marshal(plans)
+		if err != nil {
+			fmt.Println("JSON Marshal error:", err)
+			return err
+		}
+		prettyPrintJSONString(string(jsonBody))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tCURRENT\tTARGET\tPOOLS\tNODES\tBLOCKERS")
+	for _, plan := range plans {
+		blockers := "none"
+		if plan.Blocked() {
+			blockers = strings.Join(plan.Blockers, "; ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", plan.ClusterName, plan.CurrentVersion, plan.TargetVersion, strings.Join(plan.Pools, ","), plan.NodesToReplace, blockers)
+	}
+	w.Flush()
+	return nil
+}
+
+func menuGetNodePools(client *ccp.Client, clusterName string, args []string, jsonout bool) error {
+	for _, arg := range args {
+		param, _ := splitparam(arg)
+		switch param {
+		case "json", "debug":
+			// global flags
+		default:
+			fmt.Println("Error, flag ", arg, " unknown")
+		}
+	}
+
+	cluster, err := client.GetClusterByName(clusterName)
+	if err != nil {
+		fmt.Println("GetCluster error:", err)
+		return err
+	}
+
+	pools, err := client.ListNodePools(*cluster.UUID)
+	if err != nil {
+		fmt.Println("ListNodePools error:", err)
+		return err
+	}
+
+	if jsonout {
+		jsonBody, err := json.Marshal(pools)
+		if err != nil {
+			fmt.Println("JSON Marshal error:", err)
+			return err
+		}
+		prettyPrintJSONString(string(jsonBody))
+		return nil
+	}
+
+	for _, pool := range pools {
+		var name, template string
+		var size, vcpus, memory int64
+		if pool.Name != nil {
+			name = *pool.Name
+		}
+		if pool.Template != nil {
+			template = *pool.Template
+		}
+		if pool.Size != nil {
+			size = *pool.Size
+		}
+		if pool.VCPUs != nil {
+			vcpus = *pool.VCPUs
+		}
+		if pool.Memory != nil {
+			memory = *pool.Memory
+		}
+		fmt.Println("Pool: ", name, " Size: ", size, " vCPUs: ", vcpus, " Memory: ", memory, " Template: ", template)
+	}
+	return nil
+}
+
+func menuAddNodePool(client *ccp.Client, clusterName string, args []string, Settings *Defaults) error {
+	var poolname, image string
+	var gpus []string
+	var workers, vcpus, memory int64
+
+	for _, arg := range args {
+		param, value := splitparam(arg)
+		switch param {
+		case "name":
+			poolname = value
+		case "workers":
+			workers = strtoint64(value)
+		case "vcpus":
+			vcpus = strtoint64(value)
+		case "memory":
+			memory = strtoint64(value)
+		case "image":
+			image = value
+		case "gpus":
+			gpus = strings.Split(value, ",")
+		case "json", "debug":
+			// global flags
+		default:
+			fmt.Println("Error, flag ", arg, " unknown")
+		}
+	}
+
+	if poolname == "" {
+		return errors.New("Error: pool name is blank, exiting")
+	}
+	if workers < 1 {
+		fmt.Println("* Workers: workers is blank, setting to 1")
+		workers = 1
+	}
+	if vcpus < 1 {
+		vcpus = 8 // same as addcluster
+	}
+	if memory < 1 {
+		memory = 32768 // same as addcluster
+	}
+	if image == "" {
+		if Settings.CPImageDfl == "" {
+			return errors.New("Error: image is blank, and defaults blank. Either setcp or specify")
+		}
+		image = Settings.CPImageDfl
+	}
+	if Settings.SSHUser == "" || Settings.SSHKey == "" {
+		return errors.New("* SSHUser/SSHKey: SSH settings are blank, Set them in setcp. Exiting")
+	}
+
+	cluster, err := client.GetClusterByName(clusterName)
+	if err != nil {
+		fmt.Println("GetCluster error:", err)
+		return err
+	}
+
+	kubernetesversion := getKubeVerFromImage(image)
+	pool := &ccp.WorkerNodePool{
+		Name:              ccp.String(poolname),
+		Size:              ccp.Int64(workers),
+		VCPUs:             ccp.Int64(vcpus),
+		Memory:            ccp.Int64(memory),
+		Template:          ccp.String(image),
+		SSHUser:           ccp.String(Settings.SSHUser),
+		SSHKey:            ccp.String(Settings.SSHKey),
+		KubernetesVersion: ccp.String(kubernetesversion),
+	}
+	if len(gpus) > 0 {
+		pool.GPUs = &gpus
+	}
+
+	_, err = client.AddNodePool(*cluster.UUID, pool)
+	if err != nil {
+		fmt.Println("AddNodePool error:", err)
+		return err
+	}
+	fmt.Println("* Node pool", poolname, "added to cluster", clusterName)
+	return nil
+}
+
+func menuDelNodePool(client *ccp.Client, clusterName string, args []string) error {
+	var poolName string
+
+	for _, arg := range args {
+		param, _ := splitparam(arg)
+		switch param {
+		case "":
+			poolName = arg
+		case "json", "debug":
+			// global flags
+		default:
+			fmt.Println("Error, flag ", arg, " unknown")
+		}
+	}
+	if poolName == "" {
+		return errors.New("Error: pool name is blank, exiting")
+	}
+
+	cluster, err := client.GetClusterByName(clusterName)
+	if err != nil {
+		fmt.Println("GetCluster error:", err)
+		return err
+	}
+
+	err = client.DeleteNodePool(*cluster.UUID, poolName)
+	if err != nil {
+		fmt.Println("DeleteNodePool error:", err)
+		return err
+	}
+	fmt.Println("* Node pool", poolName, "deleted from cluster", clusterName)
 	return nil
 }
 
@@ -1293,6 +2399,22 @@ func main() {
 	// create the CCP Client side struct
 	client := ccp.NewClient(Settings.CPUser, Settings.CPPass, Settings.CPURL)
 	client.XAuthToken = Settings.CPToken
+	policy := ccp.ProtectionPolicy{Protected: Settings.CPProtected, Allowed: Settings.CPAllowed}
+	if Settings.CPPolicyFile != "" {
+		// the policy file adds to the patterns set with setcp
+		filePolicy, err := ccp.LoadProtectionPolicy(Settings.CPPolicyFile)
+		if err != nil {
+			fmt.Println("* Protection policy error:", err)
+			return
+		}
+		policy.Protected = append(policy.Protected, filePolicy.Protected...)
+		policy.Allowed = append(policy.Allowed, filePolicy.Allowed...)
+	}
+	err = client.SetProtectionPolicy(policy)
+	if err != nil {
+		fmt.Println("* Protected clusters error:", err)
+		return
+	}
 
 	// ---------------------------------------------
 	// check if CPTokenTime is older than 30 mins
@@ -1400,7 +2522,11 @@ func main() {
 			fmt.Println("Not implemented yet")
 			return
 		case "delcluster":
-			menuDelCluster(client, os.Args[2])
+			if len(os.Args) < 3 {
+				fmt.Println("delcluster <clustername> [--yes] [--force] [file=path]")
+				return
+			}
+			menuDelCluster(client, os.Args[2], os.Args[3:], Settings)
 			return
 		case "getcluster":
 			if len(os.Args) < 3 {
@@ -1413,16 +2539,109 @@ func main() {
 			menuGetClusters(client, jsonout)
 			return
 		case "scalecluster":
-			if len(os.Args) < 3 {
+			if len(os.Args) < 4 {
 				menuClusterHelp()
-				fmt.Println("scalecluster clustername workers=#")
+				fmt.Println("scalecluster clustername workers=# [pool=poolname]")
+				return
+			}
+			menuScaleCluster(client, os.Args[2], os.Args[3:], jsonout)
+			return
+		case "verifycluster":
+			if len(os.Args) < 3 {
+				fmt.Println("verifycluster <clustername>")
+				return
+			}
+			menuVerifyCluster(client, os.Args[2], jsonout)
+			return
+		case "apply":
+			menuApply(client, os.Args[2:], jsonout)
+			return
+		case "render":
+			menuRender(os.Args[2:])
+			return
+		case "diff":
+			menuDiff(client, os.Args[2:], jsonout)
+			return
+		case "export":
+			if len(os.Args) < 4 || os.Args[2] != "cluster" {
+				fmt.Println("export cluster <clustername> [file=path] [--ssh-keys]")
+				return
+			}
+			menuExportCluster(client, os.Args[3], os.Args[4:])
+			return
+		case "upgradecluster":
+			if len(os.Args) < 4 {
+				fmt.Println("upgradecluster <clustername> image=ccpimage")
+				return
+			}
+			menuUpgradeCluster(client, os.Args[2], os.Args[3:])
+			return
+		case "upgrade-plan":
+			if len(os.Args) < 3 {
+				fmt.Println("upgrade-plan image=ccpimage [clusters=name,name]")
+				return
+			}
+			err = menuUpgradePlan(client, os.Args[2:], jsonout)
+			if err != nil {
+				fmt.Println("Error: ", err)
+			}
+			return
+		case "getpools":
+			if len(os.Args) < 3 {
+				fmt.Println("getpools <clustername>")
+				return
+			}
+			menuGetNodePools(client, os.Args[2], os.Args[3:], jsonout)
+			return
+		case "addpool":
+			if len(os.Args) < 4 {
+				fmt.Println("addpool <clustername> name=poolname [workers=1] [vcpus=8] [memory=32768] [image=ccpimage] [gpus=type,type]")
+				return
+			}
+			err = menuAddNodePool(client, os.Args[2], os.Args[3:], Settings)
+			if err != nil {
+				fmt.Println("Error: ", err)
+			}
+			return
+		case "delpool":
+			if len(os.Args) < 4 {
+				fmt.Println("delpool <clustername> <poolname>")
+				return
+			}
+			err = menuDelNodePool(client, os.Args[2], os.Args[3:])
+			if err != nil {
+				fmt.Println("Error: ", err)
+			}
+			return
+		case "installchart":
+			if len(os.Args[1:]) < 4 {
+				fmt.Println("installchart <clustername> name=chartname url=/opt/ccp/charts/chart.tgz [namespace=ns] [description=text] [-f values.yaml]")
 				return
 			}
-			_, workers := splitparam(os.Args[3])
-			menuScaleCluster(client, os.Args[2], strtoint(workers), jsonout)
+
+			err = menuInstallChart(client, os.Args[2], os.Args[3:], jsonout)
+			if err != nil {
+				fmt.Println("Error: ", err)
+			}
+			return
+		case "endpoints":
+			err = menuGetAddonEndpoints(client, os.Args[2:], jsonout)
+			if err != nil {
+				fmt.Println("Error: ", err)
+			}
+			return
+		case "certs":
+			err = menuGetCerts(client, os.Args[2:], jsonout)
+			if err != nil {
+				fmt.Println("Error: ", err)
+			}
 			return
 		case "getkubeconf":
-			menuGetClusterKubeconfig(client, os.Args[2])
+			if len(os.Args) < 3 {
+				fmt.Println("getkubeconf <clustername> [--merge] [--set-context] [file=path]")
+				return
+			}
+			menuGetClusterKubeconfig(client, os.Args[2], os.Args[3:], Settings)
 			return
 		// Infra providers
 		case "getproviders":
@@ -1456,12 +2675,12 @@ func main() {
 			return
 		case "installaddon":
 			if len(os.Args[1:]) < 3 {
-				fmt.Println("installaddon [<clustername>] <addon>")
-				fmt.Println("Valid Addons are: monitoring, logging, istio, harbor, hx-csi, kubeflow, dashboard")
+				fmt.Println("installaddon [<clustername>] <addon> [-f values.yaml] [addons=file]")
+				fmt.Println("Valid Addons are: monitoring, logging, istio, harbor, hx-csi, kubeflow, dashboard, or any name in the cluster catalogue")
 				return
 			}
 
-			err = menuInstallClusterAddonNew(client, os.Args[2], os.Args[3], jsonout)
+			err = menuInstallClusterAddonNew(client, os.Args[2], os.Args[3], os.Args[4:], jsonout)
 			if err != nil {
 				fmt.Println("Error: ", err)
 			}
@@ -1477,15 +2696,25 @@ func main() {
 			if err != nil {
 				fmt.Println("Error: ", err)
 			}
+			return
+		case "upgradeaddon", "reconfigureaddon":
+			if len(os.Args[1:]) < 3 {
+				fmt.Println("upgradeaddon <clustername> <addon> [version=x]")
+				fmt.Println("reconfigureaddon <clustername> <addon> -f values.yaml")
+				return
+			}
 
-			fmt.Println("* Deleted cluster addon ", os.Args[3])
+			err = menuUpdateClusterAddon(client, arg, os.Args[2], os.Args[3], os.Args[4:], jsonout)
+			if err != nil {
+				fmt.Println("Error: ", err)
+			}
 			return
 		case "addclusterfromfile":
 			if len(os.Args[1:]) < 2 {
 				fmt.Println("Need cluster filename, exiting")
 				return
 			}
-			menuAddClusterFromFile(client, os.Args[2], jsonout)
+			menuAddClusterFromFile(client, os.Args[2], os.Args[3:], jsonout)
 		// print help
 		case "help":
 			menuHelp()